@@ -1,9 +1,13 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"math/big"
+	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
@@ -12,6 +16,12 @@ type BlockStat struct {
 	TotalLatency     *big.Int
 	SentMesssages    uint64
 	ReceivedMessages uint64
+	Reconciled       uint64 // sent messages relayed according to `successfulMessages`, whose relay log was missed
+	Timestamp        uint64 // of the sender block, 0 if unknown
+}
+
+func (bs BlockStat) missingReception() uint64 {
+	return bs.SentMesssages - min(bs.SentMesssages, bs.MessageCount+bs.Reconciled)
 }
 
 type DetailedIntervalStat struct {
@@ -32,12 +42,34 @@ type Aggregator struct {
 	inbox             map[Identifier]*types.Log // the key in the map refers to the sender message that is being received
 	messengerContract Contract
 	inboxContract     Contract
-	BlockStats        map[uint64]BlockStat // with respect to sender blocknum
-	LatestBlock       *uint64
+	// emits RelayedMessage on the receiver
+	receiverMessengerContract Contract
+	BlockStats                map[uint64]BlockStat // with respect to sender blocknum
+	LatestBlock               *uint64
+	anomaly                   *AnomalyDetector // nil unless anomaly detection is enabled
+	lastMessage               *time.Time
+	lastAggregation           *time.Time // last pass of the aggregate loop
+	messages                  *MessageStore
+	slo                       *SLOTracker
+	breakdowns                *Breakdowns
+	relayers                  *RelayerTracker
+	costs                     *CostTracker
+	failures                  *FailureTracker
+	reconciler                *Reconciler
+	nonces                    *NonceTracker // shared with the pairs of the same sender, has its own lock
+	replays                   *ReplayTracker
+	origins                   *OriginTracker
+	supervisor                *SupervisorTracker
+	supervisorClient          Supervisor    // nil unless a supervisor is configured
+	mu                        *sync.RWMutex // guards the maps and counters above, shared between copies
+	done                      chan struct{} // closed when the pair stops being monitored
+	stop                      *sync.Once
 }
 
 func MakeAggregator(sender, receiver *Chain, config *Config) (agg Aggregator) {
 	var LatestBlock uint64
+	lastMessage := time.Now()
+	lastAggregation := time.Now()
 	agg.messenger = make(map[Identifier]*types.Log)
 	agg.inbox = make(map[Identifier]*types.Log)
 	agg.BlockStats = make(map[uint64]BlockStat)
@@ -45,14 +77,77 @@ func MakeAggregator(sender, receiver *Chain, config *Config) (agg Aggregator) {
 	agg.Sender = sender
 	agg.Receiver = receiver
 
-	agg.inboxContract, agg.messengerContract = agg.GetContracts()
+	agg.inboxContract, agg.messengerContract, agg.receiverMessengerContract = agg.GetContracts()
 
 	agg.config = config
 	agg.LatestBlock = &LatestBlock
+	agg.lastMessage = &lastMessage
+	agg.lastAggregation = &lastAggregation
+	agg.mu = &sync.RWMutex{}
+	agg.done = make(chan struct{})
+	agg.stop = &sync.Once{}
+	agg.messages = NewMessageStore(config.MessageHistorySize)
+	agg.slo = NewSLOTracker(config.SLOs)
+	agg.breakdowns = NewBreakdowns(config.BreakdownSize)
+	agg.relayers = NewRelayerTracker(config.BreakdownSize)
+	agg.costs = NewCostTracker(config.MessageHistorySize)
+	agg.failures = NewFailureTracker(config.MessageHistorySize)
+	agg.reconciler = NewReconciler(config.MessageHistorySize)
+	agg.nonces = acquireNonceTracker(sender.ChainId.Uint64(), agg.messengerContract.Address, agg.Name(), config.MessageHistorySize)
+	agg.replays = NewReplayTracker(config.MessageHistorySize)
+	agg.origins = NewOriginTracker(config.BreakdownSize, config.MessageHistorySize)
+	agg.supervisor = NewSupervisorTracker(config.MessageHistorySize)
+
+	if config.AnomalyDetection {
+		agg.anomaly = NewAnomalyDetector(config)
+	}
 
 	return
 }
 
+// Stops the goroutines of the pair, e.g. once it left the dependency set
+func (agg *Aggregator) Stop() {
+	agg.stop.Do(func() {
+		close(agg.done)
+		agg.nonces.release(agg.Name())
+	})
+}
+
+// Sleeps for d, returns false if the pair was stopped meanwhile
+func (agg *Aggregator) sleep(d time.Duration) bool {
+	select {
+	case <-agg.done:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Returned by the Add functions when an RPC failed before anything was recorded, so the same log
+// can be added again
+type retryError struct {
+	err error
+}
+
+func (e *retryError) Error() string { return e.err.Error() }
+func (e *retryError) Unwrap() error { return e.err }
+
+// Adds a log, again while it fails on an RPC, so a flaky RPC doesn't lose messages
+func (agg *Aggregator) ingest(add func() error, errChan chan error) {
+	for {
+		err := add()
+		if err == nil {
+			return
+		}
+		errChan <- err
+
+		var retry *retryError
+		if !errors.As(err, &retry) || !agg.sleep(time.Second*time.Duration(agg.config.FetchTime)) {
+			return
+		}
+	}
+}
+
 // Add a message from the receiver
 func (agg *Aggregator) AddInboxMessage(msg *types.Log) (err error) {
 	name, data, err := agg.inboxContract.ParseEventToDic(*msg)
@@ -65,11 +160,75 @@ func (agg *Aggregator) AddInboxMessage(msg *types.Log) (err error) {
 		return err
 	}
 
+	// the inbox of the receiver also executes messages of other chains
+	if senderId.ChainId != agg.Sender.ChainId.Uint64() {
+		return
+	}
+
+	// timestamps are fetched before taking the lock, so readers don't wait on the RPCs
+	receiverTimestamp, err := agg.Receiver.GetBlockTimestamp(big.NewInt(int64(msg.BlockNumber)))
+	if err != nil {
+		return &retryError{err}
+	}
+
+	// logs are added by a single goroutine, so a message sent now is still there under the lock
+	agg.mu.RLock()
+	_, sent := agg.messenger[senderId]
+	agg.mu.RUnlock()
+
+	var senderTimestamp *big.Int
+	if sent {
+		senderTimestamp, err = agg.Sender.GetBlockTimestamp(big.NewInt(int64(senderId.BlockNumber)))
+		if err != nil {
+			return &retryError{err}
+		}
+	}
+
+	var execution *OriginExecution
+	if agg.config.TrackInboxOrigins || agg.supervisorClient != nil {
+		msgHash, _ := data["msgHash"].([32]byte)
+		execution = &OriginExecution{
+			Id:         senderId,
+			MsgHash:    msgHash,
+			TxHash:     msg.TxHash,
+			Block:      msg.BlockNumber,
+			ExecutedAt: time.Unix(receiverTimestamp.Int64(), 0).UTC(),
+		}
+	}
+
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	if execution != nil && agg.config.TrackInboxOrigins {
+		agg.origins.executed(*execution)
+	}
+	if execution != nil && agg.supervisorClient != nil {
+		agg.supervisor.enqueue(supervisorCheck{
+			message: SupervisorMessage{
+				Id:         senderId,
+				MsgHash:    execution.MsgHash,
+				ExecutedAt: uint64(execution.ExecutedAt.Unix()),
+			},
+			txHash:   msg.TxHash,
+			block:    msg.BlockNumber,
+			queuedAt: time.Now(),
+		})
+	}
+
+	// identifiers may point to any log of the sender, only SentMessage logs pair with a relay
+	if senderId.Origin != agg.messengerContract.Address {
+		log.Printf("inbox: %s of a %s log", name, senderId.Origin.Hex())
+		return
+	}
+
+	*agg.lastMessage = time.Now()
+
 	// check if message is in messenger outbox
 	messageLog, ok := agg.messenger[senderId]
 	bs := agg.GetBlockStats(senderId.BlockNumber)
 
 	bs.ReceivedMessages += 1
+	bs.Timestamp = senderId.Timestamp
 
 	agg.BlockStats[senderId.BlockNumber] = *bs
 
@@ -77,9 +236,27 @@ func (agg *Aggregator) AddInboxMessage(msg *types.Log) (err error) {
 		*agg.LatestBlock = senderId.BlockNumber
 	}
 
-	if ok {
-		agg.AddMessagePair(messageLog, msg)
+	var target string
+	if record, ok := agg.messages.pending[senderId]; ok {
+		target = record.Target.Hex()
+	}
+
+	agg.relayers.enqueue(senderId, msg.TxHash)
+
+	eventBus.Publish(EventExecuting, agg.Name(), target, ExecutingEventData{
+		Id:          senderId,
+		BlockNumber: msg.BlockNumber,
+		TxHash:      msg.TxHash,
+	})
+
+	if ok && senderTimestamp != nil {
+		agg.AddMessagePair(senderId, messageLog, msg, senderTimestamp, receiverTimestamp)
 		delete(agg.messenger, senderId)
+	} else if record := agg.messages.get(senderId); record != nil && record.Status == MessageExpired {
+		// the sent log was purged when the message expired, and its block already aggregated,
+		// so the late relay only settles the record
+		record, previous := agg.messages.relayed(senderId, msg.BlockNumber, senderId.Timestamp, receiverTimestamp.Uint64())
+		agg.settleRelay(record, previous)
 	} else {
 		agg.inbox[senderId] = msg
 	}
@@ -95,16 +272,53 @@ func (agg *Aggregator) AddMessengerMessage(msg *types.Log) (err error) {
 		return err
 	}
 
+	// RelayedMessage events on the sender belong to messages going the other way
+	if name != "SentMessage" {
+		return nil
+	}
+
+	// the messenger numbers the messages of every destination in a single sequence
+	nonce, _ := data["messageNonce"].(*big.Int)
+	if gap := agg.nonces.observeSent(nonce, msg.BlockNumber); gap != nil {
+		eventBus.Publish(EventGap, agg.Name(), "", *gap)
+		log.Printf("messenger: missed SentMessage logs with nonces %s", gap)
+	}
+
+	// the messenger of the sender also sends messages to other chains
+	destination, _ := data["destination"].(*big.Int)
+	if destination == nil || destination.Cmp(agg.Receiver.ChainId) != 0 {
+		return nil
+	}
+
+	// the identifier holds the timestamp of the sender block
 	id, err := agg.Sender.GetEventIdentifier(*msg)
 	if err != nil {
-		return err
+		return &retryError{err}
+	}
+
+	agg.mu.RLock()
+	inboxLog, received := agg.inbox[id]
+	agg.mu.RUnlock()
+
+	var receiverTimestamp *big.Int
+	if received {
+		receiverTimestamp, err = agg.Receiver.GetBlockTimestamp(big.NewInt(int64(inboxLog.BlockNumber)))
+		if err != nil {
+			return &retryError{err}
+		}
 	}
 
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	*agg.lastMessage = time.Now()
+
 	// check if message is in receiver inbox
 	messageLog, ok := agg.inbox[id]
 	bs := agg.GetBlockStats(msg.BlockNumber)
 
 	bs.SentMesssages += 1
+	bs.Timestamp = id.Timestamp
 
 	agg.BlockStats[msg.BlockNumber] = *bs
 
@@ -112,8 +326,29 @@ func (agg *Aggregator) AddMessengerMessage(msg *types.Log) (err error) {
 		*agg.LatestBlock = msg.BlockNumber
 	}
 
-	if ok {
-		agg.AddMessagePair(msg, messageLog)
+	record := agg.messages.sent(id)
+	if target, ok := data["target"].(common.Address); ok {
+		record.Target = target
+	}
+	if sender, ok := data["sender"].(common.Address); ok {
+		record.Sender = sender
+	}
+	record.Nonce = nonce
+	if message, ok := data["message"].([]byte); ok {
+		record.Payload = agg.config.payloads.decode(record.Target, message)
+	}
+	if hash, err := agg.sentMessageHash(data); err == nil {
+		record.MessageHash = &hash
+	} else {
+		log.Printf("messenger: %v", err)
+	}
+	agg.breakdowns.sent(record)
+	agg.relayers.sent(record)
+
+	eventBus.Publish(EventSent, agg.Name(), record.Target.Hex(), *record)
+
+	if ok && receiverTimestamp != nil {
+		agg.AddMessagePair(id, msg, messageLog, new(big.Int).SetUint64(id.Timestamp), receiverTimestamp)
 		delete(agg.inbox, id)
 	} else {
 		agg.messenger[id] = msg
@@ -123,6 +358,77 @@ func (agg *Aggregator) AddMessengerMessage(msg *types.Log) (err error) {
 	return
 }
 
+// When the last message was seen on either chain, or the start time if none was
+func (agg *Aggregator) LastMessageTime() time.Time {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	return *agg.lastMessage
+}
+
+// Highest sender block of a message seen so far
+func (agg *Aggregator) latestBlock() uint64 {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	return *agg.LatestBlock
+}
+
+// Records that the aggregate loop is running
+func (agg *Aggregator) aggregated() {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	*agg.lastAggregation = time.Now()
+}
+
+// When the aggregate loop last ran, or the start time if it didn't yet
+func (agg *Aggregator) LastAggregationTime() time.Time {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	return *agg.lastAggregation
+}
+
+// Add a RelayedMessage from the messenger on the receiver
+func (agg *Aggregator) AddRelayedMessage(msg *types.Log) (err error) {
+	name, data, err := agg.receiverMessengerContract.ParseEventToDic(*msg)
+	if err != nil {
+		return err
+	}
+
+	// SentMessage events on the receiver belong to messages going the other way
+	if name != "RelayedMessage" {
+		return nil
+	}
+
+	source, _ := data["source"].(*big.Int)
+	if source == nil || source.Cmp(agg.Sender.ChainId) != 0 {
+		return nil
+	}
+
+	nonce, _ := data["messageNonce"].(*big.Int)
+	hash, _ := data["messageHash"].([32]byte)
+
+	agg.mu.Lock()
+	if replay := agg.replays.observeRelayed(hash, nonce, msg.TxHash, msg.BlockNumber); replay != nil {
+		eventBus.Publish(EventReplay, agg.Name(), "", *replay)
+		log.Printf("relayed: %s relayed again by %s", replay.MessageHash.Hex(), replay.TxHash.Hex())
+	}
+	agg.mu.Unlock()
+
+	eventBus.Publish(EventRelayed, agg.Name(), "", RelayedEventData{
+		Source:       source.Uint64(),
+		MessageNonce: nonce,
+		MessageHash:  common.Hash(hash),
+		BlockNumber:  msg.BlockNumber,
+		TxHash:       msg.TxHash,
+	})
+
+	log.Printf("relayed: %s %v", name, data)
+	return
+}
+
 func (agg *Aggregator) GetBlockStats(blockNumber uint64) (bs *BlockStat) {
 	bs_v, ok := agg.BlockStats[blockNumber]
 
@@ -136,29 +442,38 @@ func (agg *Aggregator) GetBlockStats(blockNumber uint64) (bs *BlockStat) {
 	return &bs_v
 }
 
-func (agg *Aggregator) AddMessagePair(senderMsg, receiverMsg *types.Log) (err error) {
+// Pairs a message with its execution, must hold the lock. We don't want to rely on the reported
+// identifier time, so the timestamps are those of the blocks, fetched by the caller before taking
+// the lock. We keep a cache so we only ever fetch once per block
+func (agg *Aggregator) AddMessagePair(id Identifier, senderMsg, receiverMsg *types.Log, senderTimestamp, receiverTimestamp *big.Int) {
 	bs := agg.GetBlockStats(senderMsg.BlockNumber)
 
-	// We don't want to rely on the reported identifier time, so just in case we fetch the timestamp
-	// We keep a cache so we only ever fetch once per block
-	senderTimestamp, err := agg.Sender.GetBlockTimestamp(big.NewInt(int64(senderMsg.BlockNumber)))
-	if err != nil {
-		return
-	}
-
-	receiverTimestamp, err := agg.Receiver.GetBlockTimestamp(big.NewInt(int64(receiverMsg.BlockNumber)))
-	if err != nil {
-		return
-	}
+	record, previous := agg.messages.relayed(id, receiverMsg.BlockNumber, senderTimestamp.Uint64(), receiverTimestamp.Uint64())
 
 	latency := big.NewInt(0)
 	latency.Sub(receiverTimestamp, senderTimestamp)
 	bs.TotalLatency.Add(bs.TotalLatency, latency)
 	bs.MessageCount += 1
+	if previous == MessageReconciled && bs.Reconciled > 0 {
+		bs.Reconciled--
+	}
 	agg.BlockStats[senderMsg.BlockNumber] = *bs
 
+	agg.settleRelay(record, previous)
+
 	log.Printf("addMessagePair: found pair, timestamps %d %d", senderTimestamp.Uint64(), receiverTimestamp.Uint64())
-	return
+}
+
+// Accounts the relay of the record, if the message store accepted it. Must hold the lock
+func (agg *Aggregator) settleRelay(record *MessageRecord, previous MessageStatus) {
+	if record == nil {
+		return
+	}
+
+	agg.slo.observe(record, time.Now())
+	agg.breakdowns.transition(record, previous)
+	agg.relayers.observeLatency(record)
+	eventBus.Publish(EventPaired, agg.Name(), record.Target.Hex(), *record)
 }
 
 func (agg *Aggregator) AggregateLatestBlocks(blockAmount uint64) (ds DetailedIntervalStat) {
@@ -171,6 +486,9 @@ func (agg *Aggregator) AggregateLatestBlocks(blockAmount uint64) (ds DetailedInt
 		MissingReception: 0,
 	}
 
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
 	if agg.config.PurgeOldMessages {
 		for key := range agg.inbox {
 			if key.BlockNumber <= *agg.LatestBlock-2*agg.config.AggregateBlockAmount {
@@ -180,6 +498,12 @@ func (agg *Aggregator) AggregateLatestBlocks(blockAmount uint64) (ds DetailedInt
 		for key := range agg.messenger {
 			if key.BlockNumber <= *agg.LatestBlock-2*agg.config.AggregateBlockAmount {
 				delete(agg.messenger, key)
+
+				if record := agg.messages.expired(key); record != nil {
+					agg.slo.observe(record, time.Now())
+					agg.breakdowns.transition(record, MessagePending)
+					eventBus.Publish(EventExpired, agg.Name(), record.Target.Hex(), *record)
+				}
 			}
 		}
 
@@ -193,7 +517,7 @@ func (agg *Aggregator) AggregateLatestBlocks(blockAmount uint64) (ds DetailedInt
 		if int64(key) >= int64(*agg.LatestBlock)-int64(blockAmount) {
 			ds.MessageCount += val.MessageCount
 			ds.TotalLatency.Add(ds.TotalLatency, val.TotalLatency)
-			ds.MissingReception += val.SentMesssages - val.MessageCount
+			ds.MissingReception += val.missingReception()
 			ds.MissingRelay += val.ReceivedMessages - val.MessageCount
 			ds.ReceivedMessages += val.ReceivedMessages
 			ds.SentMesssages += val.SentMesssages
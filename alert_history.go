@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+type AlertEventKind string
+
+const (
+	AlertFiring       AlertEventKind = "firing"
+	AlertResolved     AlertEventKind = "resolved"
+	AlertAcknowledged AlertEventKind = "acknowledged"
+)
+
+// A state transition of an alert, as stored in the history
+type AlertEvent struct {
+	Time      time.Time            `json:"time"`
+	Event     AlertEventKind       `json:"event"`
+	Rule      string               `json:"rule"`
+	Type      string               `json:"type"`
+	Severity  string               `json:"severity"`
+	Pair      string               `json:"pair"`
+	Value     string               `json:"value"`
+	Threshold string               `json:"threshold"`
+	Stats     DetailedIntervalStat `json:"stats"`
+}
+
+// Filters for querying the alert history, zero values match everything
+type AlertHistoryQuery struct {
+	From  time.Time
+	To    time.Time
+	Rule  string
+	Pair  string
+	Limit int
+}
+
+func (q AlertHistoryQuery) matches(ev AlertEvent) bool {
+	return (q.From.IsZero() || !ev.Time.Before(q.From)) &&
+		(q.To.IsZero() || !ev.Time.After(q.To)) &&
+		(q.Rule == "" || q.Rule == ev.Rule) &&
+		(q.Pair == "" || q.Pair == ev.Pair)
+}
+
+// Appends an event to the history, must hold the lock
+func (m *AlertManager) record(kind AlertEventKind, alert *ActiveAlert) {
+	ev := AlertEvent{
+		Time:      time.Now().UTC(),
+		Event:     kind,
+		Rule:      alert.Rule,
+		Type:      alert.Type,
+		Severity:  alert.Severity,
+		Pair:      alert.Pair,
+		Value:     alert.Value,
+		Threshold: alert.Threshold,
+		Stats:     alert.stats,
+	}
+
+	eventBus.Publish(EventAlert, ev.Pair, "", ev)
+
+	m.history = append(m.history, ev)
+	if len(m.history) > m.config.AlertHistorySize {
+		m.history = m.history[len(m.history)-m.config.AlertHistorySize:]
+	}
+
+	if m.config.AlertHistoryFile == "" {
+		return
+	}
+
+	if err := appendJSONLine(m.config.AlertHistoryFile, ev); err != nil {
+		log.Printf("failed to persist alert event: %v", err)
+	}
+}
+
+// Returns the most recent events matching the query, oldest first
+func (m *AlertManager) History(q AlertHistoryQuery) []AlertEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := make([]AlertEvent, 0)
+	for i := len(m.history) - 1; i >= 0; i-- {
+		if q.Limit > 0 && len(events) >= q.Limit {
+			break
+		}
+
+		if q.matches(m.history[i]) {
+			events = append(events, m.history[i])
+		}
+	}
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+
+	return events
+}
+
+func (m *AlertManager) loadHistory() error {
+	if m.config.AlertHistoryFile == "" {
+		return nil
+	}
+
+	file, err := os.Open(m.config.AlertHistoryFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var ev AlertEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return fmt.Errorf("failed to parse alert history: %w", err)
+		}
+
+		m.history = append(m.history, ev)
+		if len(m.history) > 2*m.config.AlertHistorySize {
+			m.history = append(m.history[:0], m.history[len(m.history)-m.config.AlertHistorySize:]...)
+		}
+	}
+
+	if len(m.history) > m.config.AlertHistorySize {
+		m.history = m.history[len(m.history)-m.config.AlertHistorySize:]
+	}
+
+	return scanner.Err()
+}
+
+func appendJSONLine(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
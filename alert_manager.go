@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// expired silences are kept around for a while so they can still be listed
+const silenceRetention = 7 * 24 * time.Hour
+
+// Mutes every alert matching all of its non-empty matchers between StartsAt and EndsAt
+type Silence struct {
+	ID        uint64    `json:"id"`
+	Rule      string    `json:"rule,omitempty"`
+	Severity  string    `json:"severity,omitempty"`
+	Pair      string    `json:"pair,omitempty"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (s Silence) Active(at time.Time) bool {
+	return !at.Before(s.StartsAt) && at.Before(s.EndsAt)
+}
+
+func (s Silence) Matches(check AlertCheck, at time.Time) bool {
+	return s.Active(at) &&
+		(s.Rule == "" || s.Rule == check.Rule) &&
+		(s.Severity == "" || s.Severity == check.Severity) &&
+		(s.Pair == "" || s.Pair == check.Pair)
+}
+
+// An alert whose rule is currently firing
+type ActiveAlert struct {
+	Rule           string     `json:"rule"`
+	Type           string     `json:"type"`
+	Severity       string     `json:"severity"`
+	Pair           string     `json:"pair"`
+	Value          string     `json:"value"`
+	Threshold      string     `json:"threshold"`
+	Since          time.Time  `json:"since"`
+	Acknowledged   bool       `json:"acknowledged"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	Silenced       bool       `json:"silenced"`
+
+	stats DetailedIntervalStat
+}
+
+// Keeps track of firing alerts and decides which ones are notified
+type AlertManager struct {
+	mu            sync.Mutex
+	config        *Config
+	nextSilenceId uint64
+	silences      []*Silence
+	active        map[string]*ActiveAlert
+	history       []AlertEvent
+	lastEval      time.Time
+}
+
+var alertManager *AlertManager
+
+func AlertManagerInit(config *Config) error {
+	alertManager = &AlertManager{
+		config:        config,
+		nextSilenceId: 1,
+		active:        make(map[string]*ActiveAlert),
+		lastEval:      time.Now().UTC(),
+	}
+
+	if err := alertManager.load(); err != nil {
+		return err
+	}
+
+	return alertManager.loadHistory()
+}
+
+func alertKey(rule, pair string) string {
+	return rule + "@" + pair
+}
+
+// Updates the state of the rule and notifies it, unless silenced or acknowledged
+func (m *AlertManager) Evaluate(check AlertCheck, stats DetailedIntervalStat) error {
+	m.mu.Lock()
+
+	key := alertKey(check.Rule, check.Pair)
+	now := time.Now().UTC()
+	m.lastEval = now
+
+	alert, ok := m.active[key]
+
+	if !check.Firing {
+		if ok {
+			alert.Value = check.Value
+			alert.stats = stats
+			m.record(AlertResolved, alert)
+			delete(m.active, key)
+		}
+		m.mu.Unlock()
+		return nil
+	}
+
+	if !ok {
+		alert = &ActiveAlert{
+			Rule:      check.Rule,
+			Type:      check.Type,
+			Severity:  check.Severity,
+			Pair:      check.Pair,
+			Value:     check.Value,
+			Threshold: check.Threshold,
+			Since:     now,
+			stats:     stats,
+		}
+		m.active[key] = alert
+		m.record(AlertFiring, alert)
+	}
+
+	alert.Value = check.Value
+	alert.Threshold = check.Threshold
+	alert.stats = stats
+
+	notify := !alert.Acknowledged && !m.silenced(check, now)
+	m.mu.Unlock()
+
+	if !notify {
+		return nil
+	}
+
+	return SendAlert(check, stats, m.config)
+}
+
+// Records an evaluation cycle that may not have had any rule to evaluate
+func (m *AlertManager) MarkEvaluated() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastEval = time.Now().UTC()
+}
+
+func (m *AlertManager) LastEvaluation() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lastEval
+}
+
+// Returns the firing alerts, oldest first
+func (m *AlertManager) Active() []ActiveAlert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	alerts := make([]ActiveAlert, 0, len(m.active))
+
+	for _, alert := range m.active {
+		a := *alert
+		a.Silenced = m.silenced(AlertCheck{Rule: a.Rule, Severity: a.Severity, Pair: a.Pair}, now)
+		alerts = append(alerts, a)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Since.Before(alerts[j].Since) })
+
+	return alerts
+}
+
+// Must hold the lock
+func (m *AlertManager) silenced(check AlertCheck, at time.Time) bool {
+	for _, s := range m.silences {
+		if s.Matches(check, at) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolves the firing alerts of a pair that stopped being monitored
+func (m *AlertManager) ResolvePair(pair string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, alert := range m.active {
+		if alert.Pair == pair {
+			m.record(AlertResolved, alert)
+			delete(m.active, key)
+		}
+	}
+}
+
+// Stops re-notifying a firing alert until it resolves
+func (m *AlertManager) Acknowledge(rule, pair string) (ActiveAlert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alert, ok := m.active[alertKey(rule, pair)]
+	if !ok {
+		return ActiveAlert{}, fmt.Errorf("no firing alert for rule %q on pair %q", rule, pair)
+	}
+
+	if !alert.Acknowledged {
+		now := time.Now().UTC()
+		alert.Acknowledged = true
+		alert.AcknowledgedAt = &now
+		m.record(AlertAcknowledged, alert)
+	}
+
+	return *alert, nil
+}
+
+func (m *AlertManager) AddSilence(s Silence) (Silence, error) {
+	now := time.Now().UTC()
+
+	if s.StartsAt.IsZero() {
+		s.StartsAt = now
+	}
+
+	if !s.EndsAt.After(s.StartsAt) {
+		return Silence{}, fmt.Errorf("endsAt must be after startsAt")
+	}
+
+	switch s.Severity {
+	case "", SeverityWarning, SeverityCritical:
+	default:
+		return Silence{}, fmt.Errorf("severity must be %q or %q", SeverityWarning, SeverityCritical)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s.ID = m.nextSilenceId
+	s.CreatedAt = now
+	m.nextSilenceId += 1
+	m.silences = append(m.silences, &s)
+	m.persist()
+
+	return s, nil
+}
+
+// Ends a silence immediately
+func (m *AlertManager) ExpireSilence(id uint64) (Silence, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.silences {
+		if s.ID == id {
+			now := time.Now().UTC()
+			if s.EndsAt.After(now) {
+				s.EndsAt = now
+				m.persist()
+			}
+			return *s, nil
+		}
+	}
+
+	return Silence{}, fmt.Errorf("silence %d not found", id)
+}
+
+func (m *AlertManager) Silences(activeOnly bool) []Silence {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	kept := m.silences[:0]
+	silences := make([]Silence, 0)
+
+	for _, s := range m.silences {
+		if now.Sub(s.EndsAt) > silenceRetention {
+			continue
+		}
+		kept = append(kept, s)
+
+		if !activeOnly || s.Active(now) {
+			silences = append(silences, *s)
+		}
+	}
+	m.silences = kept
+
+	return silences
+}
+
+// Writes the silences to disk, must hold the lock
+func (m *AlertManager) persist() {
+	if m.config.SilencesFile == "" {
+		return
+	}
+
+	data, err := json.Marshal(m.silences)
+	if err != nil {
+		log.Printf("failed to encode silences: %v", err)
+		return
+	}
+
+	if err := writeFileAtomic(m.config.SilencesFile, data); err != nil {
+		log.Printf("failed to persist silences: %v", err)
+	}
+}
+
+func (m *AlertManager) load() error {
+	if m.config.SilencesFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.config.SilencesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &m.silences); err != nil {
+		return fmt.Errorf("failed to parse silences: %w", err)
+	}
+
+	for _, s := range m.silences {
+		m.nextSilenceId = max(m.nextSilenceId, s.ID+1)
+	}
+
+	return nil
+}
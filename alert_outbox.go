@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+const (
+	deliveryBaseBackoff = time.Second
+	deliveryMaxBackoff  = 10 * time.Minute
+	// how many finished deliveries are kept in memory for the API
+	deliveryHistorySize = 100
+)
+
+// A rendered alert waiting to be delivered to a single channel
+type Delivery struct {
+	ID          uint64         `json:"id"`
+	Channel     string         `json:"channel"`
+	AlertType   string         `json:"alertType"`
+	Payload     string         `json:"payload"`
+	Status      DeliveryStatus `json:"status"`
+	Attempts    int            `json:"attempts"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	NextAttempt time.Time      `json:"nextAttempt"`
+	DeliveredAt *time.Time     `json:"deliveredAt,omitempty"`
+	LastError   string         `json:"lastError,omitempty"`
+
+	inFlight bool
+}
+
+// Queues alerts and delivers them asynchronously, independently per channel.
+// Undelivered alerts are persisted so they survive restarts
+type AlertOutbox struct {
+	mu       sync.Mutex
+	config   *Config
+	nextId   uint64
+	pending  []*Delivery
+	finished []*Delivery
+}
+
+var alertOutbox *AlertOutbox
+
+func AlertOutboxInit(config *Config) (err error) {
+	alertOutbox = &AlertOutbox{
+		config: config,
+		nextId: 1,
+	}
+
+	if err = alertOutbox.load(); err != nil {
+		return
+	}
+
+	go alertOutbox.run()
+
+	return nil
+}
+
+func (o *AlertOutbox) Enqueue(channel, alertType, payload string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now().UTC()
+	o.pending = append(o.pending, &Delivery{
+		ID:          o.nextId,
+		Channel:     channel,
+		AlertType:   alertType,
+		Payload:     payload,
+		Status:      DeliveryPending,
+		CreatedAt:   now,
+		NextAttempt: now,
+	})
+	o.nextId += 1
+
+	o.persist()
+}
+
+// Returns pending deliveries followed by the most recent finished ones
+func (o *AlertOutbox) Deliveries(status DeliveryStatus) []Delivery {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	deliveries := make([]Delivery, 0)
+	for _, list := range [][]*Delivery{o.pending, o.finished} {
+		for _, d := range list {
+			if status == "" || d.Status == status {
+				deliveries = append(deliveries, *d)
+			}
+		}
+	}
+
+	return deliveries
+}
+
+func (o *AlertOutbox) run() {
+	for {
+		o.mu.Lock()
+		now := time.Now()
+		for _, d := range o.pending {
+			if !d.inFlight && !d.NextAttempt.After(now) {
+				d.inFlight = true
+				go o.attempt(d)
+			}
+		}
+		o.mu.Unlock()
+
+		time.Sleep(time.Second)
+	}
+}
+
+// Tries to deliver once, and reschedules with exponential backoff on failure
+func (o *AlertOutbox) attempt(d *Delivery) {
+	var err error
+
+	ch, ok := getAlertChannel(d.Channel)
+	if !ok {
+		err = fmt.Errorf("unknown alert channel %q", d.Channel)
+	} else if !ch.enabled(o.config) {
+		err = fmt.Errorf("alert channel %q is not configured", d.Channel)
+	} else {
+		err = ch.send(d.Payload, o.config)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	d.inFlight = false
+	d.Attempts += 1
+	now := time.Now().UTC()
+
+	if err == nil {
+		d.Status = DeliveryDelivered
+		d.DeliveredAt = &now
+		d.LastError = ""
+		o.finish(d)
+		return
+	}
+
+	d.LastError = err.Error()
+	log.Printf("alert delivery %d to %s failed (attempt %d): %v", d.ID, d.Channel, d.Attempts, err)
+
+	if d.Attempts >= o.config.AlertMaxAttempts {
+		d.Status = DeliveryFailed
+		o.finish(d)
+		return
+	}
+
+	backoff := deliveryBaseBackoff << (d.Attempts - 1)
+	if backoff <= 0 || backoff > deliveryMaxBackoff {
+		backoff = deliveryMaxBackoff
+	}
+
+	var de *deliveryError
+	if errors.As(err, &de) && de.RetryAfter > 0 {
+		backoff = de.RetryAfter
+	}
+
+	d.NextAttempt = now.Add(backoff)
+	o.persist()
+}
+
+// Moves a delivery out of the pending queue, must hold the lock
+func (o *AlertOutbox) finish(d *Delivery) {
+	for i, p := range o.pending {
+		if p == d {
+			o.pending = append(o.pending[:i], o.pending[i+1:]...)
+			break
+		}
+	}
+
+	o.finished = append(o.finished, d)
+	if len(o.finished) > deliveryHistorySize {
+		o.finished = o.finished[len(o.finished)-deliveryHistorySize:]
+	}
+
+	o.persist()
+}
+
+// Writes the pending queue to disk, must hold the lock
+func (o *AlertOutbox) persist() {
+	if o.config.AlertOutboxFile == "" {
+		return
+	}
+
+	data, err := json.Marshal(o.pending)
+	if err != nil {
+		log.Printf("failed to encode alert outbox: %v", err)
+		return
+	}
+
+	if err := writeFileAtomic(o.config.AlertOutboxFile, data); err != nil {
+		log.Printf("failed to persist alert outbox: %v", err)
+	}
+}
+
+func (o *AlertOutbox) load() error {
+	if o.config.AlertOutboxFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(o.config.AlertOutboxFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &o.pending); err != nil {
+		return fmt.Errorf("failed to parse alert outbox: %w", err)
+	}
+
+	for _, d := range o.pending {
+		o.nextId = max(o.nextId, d.ID+1)
+	}
+
+	if len(o.pending) > 0 {
+		log.Printf("loaded %d undelivered alerts from %s", len(o.pending), o.config.AlertOutboxFile)
+	}
+
+	return nil
+}
+
+// Writes to a temporary file first so a crash never leaves a truncated file behind
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
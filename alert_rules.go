@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// The outcome of evaluating a single alert rule for a chain pair
+type AlertCheck struct {
+	Rule      string // identifier of the rule, used for silences and acknowledgements
+	Type      string // human readable name of the rule
+	Severity  string
+	Pair      string
+	Firing    bool
+	Value     string
+	Threshold string
+}
+
+// Evaluates the built-in alert rules over the latest aggregated stats
+func (agg *Aggregator) AlertChecks(stats DetailedIntervalStat) (checks []AlertCheck) {
+	config := agg.config
+	pair := agg.Name()
+
+	if config.AlertAvgLatencyMin != 0 {
+		checks = append(checks, AlertCheck{
+			Rule:      "avgLatency",
+			Type:      "Average Latency",
+			Severity:  SeverityWarning,
+			Pair:      pair,
+			Firing:    stats.AvgLatency > config.AlertAvgLatencyMin,
+			Value:     fmt.Sprintf("%f", stats.AvgLatency),
+			Threshold: fmt.Sprintf("%f", config.AlertAvgLatencyMin),
+		})
+	}
+
+	if config.AlertMissingReceptionMin != 0 {
+		checks = append(checks, AlertCheck{
+			Rule:      "missingReception",
+			Type:      "Missing Reception",
+			Severity:  SeverityCritical,
+			Pair:      pair,
+			Firing:    stats.MissingReception > config.AlertMissingReceptionMin,
+			Value:     fmt.Sprintf("%d", stats.MissingReception),
+			Threshold: fmt.Sprintf("%d", config.AlertMissingReceptionMin),
+		})
+	}
+
+	if config.AlertMissingRelayMin != 0 {
+		checks = append(checks, AlertCheck{
+			Rule:      "missingRelay",
+			Type:      "Missing Relay",
+			Severity:  SeverityCritical,
+			Pair:      pair,
+			Firing:    stats.MissingRelay > config.AlertMissingRelayMin,
+			Value:     fmt.Sprintf("%d", stats.MissingRelay),
+			Threshold: fmt.Sprintf("%d", config.AlertMissingRelayMin),
+		})
+	}
+
+	if agg.anomaly != nil {
+		checks = append(checks, agg.anomaly.Checks(stats, pair)...)
+	}
+
+	if config.AlertCostSpikeRatio != 0 {
+		agg.mu.Lock()
+		check, ok := agg.costs.check(config, pair)
+		agg.mu.Unlock()
+
+		if ok {
+			checks = append(checks, check)
+		}
+	}
+
+	if config.AlertFailedExecutionsMin != 0 {
+		agg.mu.Lock()
+		checks = append(checks, agg.failures.check(config, pair))
+		agg.mu.Unlock()
+	}
+
+	if config.AlertMissedRelaysMin != 0 {
+		agg.mu.Lock()
+		checks = append(checks, agg.reconciler.check(config, pair))
+		agg.mu.Unlock()
+	}
+
+	// the pairs of a sender share its nonces, a single one alerts on the gaps
+	if config.AlertNonceGaps && agg.nonces.owner() == pair {
+		checks = append(checks, agg.nonces.checkGaps(pair))
+	}
+
+	if config.AlertRelayReplays {
+		agg.mu.Lock()
+		checks = append(checks, agg.replays.checkReplays(pair))
+		agg.mu.Unlock()
+	}
+
+	if agg.supervisorClient != nil && config.AlertSupervisorMismatch {
+		agg.mu.Lock()
+		checks = append(checks, agg.supervisor.checkDisagreements(pair))
+		agg.mu.Unlock()
+	}
+
+	if agg.supervisorClient != nil && config.AlertCrossSafeLagBlocks != 0 {
+		agg.mu.Lock()
+		checks = append(checks, agg.supervisor.checkLag(config, pair))
+		agg.mu.Unlock()
+	}
+
+	if len(config.AlertInflightValue) != 0 {
+		checks = append(checks, agg.inflightCheck(pair))
+	}
+
+	checks = append(checks, agg.RuleChecks()...)
+
+	// Custom alerts can be added here
+
+	return
+}
@@ -2,114 +2,194 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 )
 
-func SendAlert(alertType, alertValue string, stats DetailedIntervalStat, config *Config) error {
-	statsString, err := json.Marshal(stats)
+// Sends hang on unresponsive channels otherwise, keeping their delivery in flight
+var deliveryClient = &http.Client{Timeout: 10 * time.Second}
 
-	if err != nil {
-		return err
-	}
+// A destination for alerts, knows how to render and deliver a message
+type alertChannel struct {
+	name    string
+	enabled func(config *Config) bool
+	render  func(msg AlertMessage, config *Config) (string, error)
+	send    func(payload string, config *Config) error
+}
 
-	message := fmt.Sprintf("Alert: %s at %s\n\n%s", alertType, alertValue, statsString)
-	if config.TelegramToken != "" {
-		err = telegramMessage(message, config)
+var alertChannels = []alertChannel{
+	{
+		name:    "telegram",
+		enabled: func(config *Config) bool { return config.TelegramToken != "" },
+		render: func(msg AlertMessage, config *Config) (string, error) {
+			return renderTemplate(config.templates.telegram, msg)
+		},
+		send: telegramMessage,
+	},
+	{
+		name:    "discord",
+		enabled: func(config *Config) bool { return config.DiscordWebhookURL != "" },
+		render: func(msg AlertMessage, config *Config) (string, error) {
+			return renderJSONTemplate(config.templates.discord, msg)
+		},
+		send: func(payload string, config *Config) error {
+			return postJSON(config.DiscordWebhookURL, payload)
+		},
+	},
+	{
+		name:    "slack",
+		enabled: func(config *Config) bool { return config.SlackWebhookURL != "" },
+		render: func(msg AlertMessage, config *Config) (string, error) {
+			return renderJSONTemplate(config.templates.slack, msg)
+		},
+		send: func(payload string, config *Config) error {
+			return postJSON(config.SlackWebhookURL, payload)
+		},
+	},
+	{
+		name:    "custom",
+		enabled: func(config *Config) bool { return config.CustomWebhookURL != "" },
+		render: func(msg AlertMessage, config *Config) (string, error) {
+			return renderJSONTemplate(config.templates.customWebhook, msg)
+		},
+		send: func(payload string, config *Config) error {
+			return postJSON(config.CustomWebhookURL, payload)
+		},
+	},
+}
 
-		if err != nil {
-			return err
+func getAlertChannel(name string) (alertChannel, bool) {
+	for _, ch := range alertChannels {
+		if ch.name == name {
+			return ch, true
 		}
 	}
 
-	if config.DiscordWebhookURL != "" {
-		err = discordMessage(message, config)
+	return alertChannel{}, false
+}
 
-		if err != nil {
-			return err
-		}
+// Renders the alert for every enabled channel and queues it on the outbox.
+// Delivery happens asynchronously, so only rendering errors are returned
+func SendAlert(check AlertCheck, stats DetailedIntervalStat, config *Config) error {
+	msg := AlertMessage{
+		Rule:      check.Rule,
+		Type:      check.Type,
+		Severity:  check.Severity,
+		Pair:      check.Pair,
+		Value:     check.Value,
+		Threshold: check.Threshold,
+		Stats:     stats,
+		Time:      time.Now().UTC(),
 	}
 
-	if config.CustomWebhookURL != "" {
-		err = customWebhookMessage(message, config)
+	var renderErr error
+
+	for _, ch := range alertChannels {
+		if !ch.enabled(config) {
+			continue
+		}
 
+		payload, err := ch.render(msg, config)
 		if err != nil {
-			return err
+			// keep going, a broken template must not block the other channels
+			renderErr = fmt.Errorf("failed to render %s alert: %w", ch.name, err)
+			continue
 		}
+
+		alertOutbox.Enqueue(ch.name, check.Type, payload)
 	}
 
-	return nil
+	return renderErr
 }
 
-func telegramMessage(message string, config *Config) error {
-	baseURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", config.TelegramToken)
-	params := url.Values{}
-	params.Add("text", message)
-	params.Add("chat_id", config.TelegramChatId)
+// Returned when a channel answers with an unexpected status code
+type deliveryError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
 
-	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
+func (e *deliveryError) Error() string {
+	return fmt.Sprintf("failed to send message, status code: %d", e.StatusCode)
+}
 
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		return err
+func checkDeliveryResponse(resp *http.Response) error {
+	// Discord answers 204 when the webhook is not asked to wait for the message
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
+		return nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to send message, status code: %d", resp.StatusCode)
+	de := &deliveryError{StatusCode: resp.StatusCode}
+	// only rate limiting and unavailability tell when to retry
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		de.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
 	}
 
-	return nil
+	return de
 }
 
-func discordMessage(message string, config *Config) error {
-	baseURL := config.DiscordWebhookURL
-
-	var body struct {
-		Content string `json:"content"`
+// Retry-After is either a number of seconds or an HTTP date, capped to the longest backoff
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
 	}
 
-	body.Content = message
+	var retryAfter time.Duration
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		retryAfter = time.Duration(min(seconds, deliveryMaxBackoff.Seconds()) * float64(time.Second))
+	} else if date, err := http.ParseTime(value); err == nil {
+		retryAfter = max(time.Until(date), 0)
+	}
 
-	bodyJson, _ := json.Marshal(body)
+	return min(retryAfter, deliveryMaxBackoff)
+}
 
-	resp, err := http.Post(baseURL, "application/json", bytes.NewBuffer(bodyJson))
-	if err != nil {
+// The URLs of channels hold their credentials, the bot token or the webhook secret, so errors
+// only keep their host before being logged or stored in the outbox
+func redactURLError(err error) error {
+	var ue *url.Error
+	if !errors.As(err, &ue) {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to send message, status code: %d", resp.StatusCode)
+	redacted := "<redacted>"
+	if u, parseErr := url.Parse(ue.URL); parseErr == nil {
+		redacted = u.Scheme + "://" + u.Host + "/<redacted>"
 	}
 
-	return nil
-
+	return &url.Error{Op: ue.Op, URL: redacted, Err: ue.Err}
 }
 
-func customWebhookMessage(message string, config *Config) error {
-	baseURL := config.CustomWebhookURL
+func telegramMessage(message string, config *Config) error {
+	baseURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", config.TelegramToken)
+	params := url.Values{}
+	params.Add("text", message)
+	params.Add("chat_id", config.TelegramChatId)
 
-	var body struct {
-		Content string `json:"text"`
+	if config.TelegramParseMode != "" {
+		params.Add("parse_mode", config.TelegramParseMode)
 	}
 
-	body.Content = message
-
-	bodyJson, _ := json.Marshal(body)
+	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
-	resp, err := http.Post(baseURL, "application/json", bytes.NewBuffer(bodyJson))
+	resp, err := deliveryClient.Get(fullURL)
 	if err != nil {
-		return err
+		return redactURLError(err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to send message, status code: %d", resp.StatusCode)
-	}
+	return checkDeliveryResponse(resp)
+}
 
-	return nil
+func postJSON(baseURL string, body string) error {
+	resp, err := deliveryClient.Post(baseURL, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		return redactURLError(err)
+	}
+	defer resp.Body.Close()
 
+	return checkDeliveryResponse(resp)
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Data available to every alert template
+type AlertMessage struct {
+	Rule      string
+	Type      string
+	Severity  string
+	Pair      string
+	Value     string
+	Threshold string
+	Stats     DetailedIntervalStat
+	Time      time.Time
+}
+
+// Plain text version of the alert, same format used before templates existed
+func (msg AlertMessage) Text() string {
+	statsString, _ := json.Marshal(msg.Stats)
+	return fmt.Sprintf("Alert: %s at %s\n\n%s", msg.Type, msg.Value, statsString)
+}
+
+const defaultTelegramTemplate = `<b>Alert: {{html .Type}}</b>
+Value: <code>{{html .Value}}</code>
+
+Paired messages: {{.Stats.MessageCount}}
+Sent / received: {{.Stats.SentMesssages}} / {{.Stats.ReceivedMessages}}
+Average latency: {{printf "%.2f" .Stats.AvgLatency}}s
+Missing reception: {{.Stats.MissingReception}}
+Missing relay: {{.Stats.MissingRelay}}`
+
+const defaultDiscordTemplate = `{
+  "embeds": [{
+    "title": {{json (printf "Alert: %s" .Type)}},
+    "description": {{json (printf "Value: %s" .Value)}},
+    "color": 15158332,
+    "timestamp": {{json .Time}},
+    "fields": [
+      {"name": "Paired messages", "value": "{{.Stats.MessageCount}}", "inline": true},
+      {"name": "Sent", "value": "{{.Stats.SentMesssages}}", "inline": true},
+      {"name": "Received", "value": "{{.Stats.ReceivedMessages}}", "inline": true},
+      {"name": "Average latency", "value": "{{printf "%.2f" .Stats.AvgLatency}}s", "inline": true},
+      {"name": "Missing reception", "value": "{{.Stats.MissingReception}}", "inline": true},
+      {"name": "Missing relay", "value": "{{.Stats.MissingRelay}}", "inline": true}
+    ]
+  }]
+}`
+
+const defaultSlackTemplate = `{
+  "text": {{json (printf "Alert: %s at %s" .Type .Value)}},
+  "blocks": [
+    {"type": "header", "text": {"type": "plain_text", "text": {{json (printf "Alert: %s" .Type)}}}},
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{json (printf "*Value:* %s" .Value)}}}},
+    {"type": "section", "fields": [
+      {"type": "mrkdwn", "text": "*Paired messages*\n{{.Stats.MessageCount}}"},
+      {"type": "mrkdwn", "text": "*Average latency*\n{{printf "%.2f" .Stats.AvgLatency}}s"},
+      {"type": "mrkdwn", "text": "*Missing reception*\n{{.Stats.MissingReception}}"},
+      {"type": "mrkdwn", "text": "*Missing relay*\n{{.Stats.MissingRelay}}"}
+    ]}
+  ]
+}`
+
+const defaultCustomWebhookTemplate = `{"text": {{json .Text}}}`
+
+// Compiled templates for every alert channel
+type alertTemplates struct {
+	telegram      *template.Template
+	discord       *template.Template
+	slack         *template.Template
+	customWebhook *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	// encodes any value as a JSON literal, for templates producing JSON bodies
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// escapes text for Telegram's MarkdownV2 parse mode
+	"markdown": func(s string) string {
+		var b strings.Builder
+		for _, r := range s {
+			if strings.ContainsRune("_*[]()~`>#+-=|{}.!\\", r) {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	},
+}
+
+func parseAlertTemplates(config *Config) (t alertTemplates, err error) {
+	parse := func(name, text, fallback string) (*template.Template, error) {
+		if text == "" {
+			text = fallback
+		}
+
+		tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		return tmpl, nil
+	}
+
+	if t.telegram, err = parse("telegramTemplate", config.TelegramTemplate, defaultTelegramTemplate); err != nil {
+		return
+	}
+	if t.discord, err = parse("discordTemplate", config.DiscordTemplate, defaultDiscordTemplate); err != nil {
+		return
+	}
+	if t.slack, err = parse("slackTemplate", config.SlackTemplate, defaultSlackTemplate); err != nil {
+		return
+	}
+	if t.customWebhook, err = parse("customWebhookTemplate", config.CustomWebhookTemplate, defaultCustomWebhookTemplate); err != nil {
+		return
+	}
+
+	return
+}
+
+func renderTemplate(tmpl *template.Template, msg AlertMessage) (string, error) {
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, msg); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Renders a template that must produce a JSON request body
+func renderJSONTemplate(tmpl *template.Template, msg AlertMessage) (string, error) {
+	body, err := renderTemplate(tmpl, msg)
+	if err != nil {
+		return "", err
+	}
+
+	if !json.Valid([]byte(body)) {
+		return "", fmt.Errorf("%s did not render valid JSON", tmpl.Name())
+	}
+
+	return body, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// The standard deviation used for z-scores is never below this fraction of the baseline mean,
+// so that perfectly steady metrics don't alert on the smallest change
+const anomalyMinRelativeStd = 0.1
+
+// Exponentially weighted moving mean and variance of a metric
+type ewmaBaseline struct {
+	mean     float64
+	variance float64
+	samples  int
+}
+
+func (b *ewmaBaseline) update(x, alpha float64) {
+	if b.samples == 0 {
+		b.mean = x
+	} else {
+		diff := x - b.mean
+		incr := alpha * diff
+		b.mean += incr
+		b.variance = (1 - alpha) * (b.variance + diff*incr)
+	}
+
+	b.samples += 1
+}
+
+func (b *ewmaBaseline) zscore(x float64) float64 {
+	std := max(math.Sqrt(b.variance), anomalyMinRelativeStd*math.Abs(b.mean), 1e-9)
+	return (x - b.mean) / std
+}
+
+// Learns a baseline for the aggregated stream and flags significant deviations
+type AnomalyDetector struct {
+	config       *Config
+	latency      ewmaBaseline
+	throughput   ewmaBaseline
+	missingRatio ewmaBaseline
+}
+
+func NewAnomalyDetector(config *Config) *AnomalyDetector {
+	return &AnomalyDetector{config: config}
+}
+
+// Scores the window against the baseline learned so far, then learns from it.
+// Must be called once per aggregation window
+func (d *AnomalyDetector) Checks(stats DetailedIntervalStat, pair string) (checks []AlertCheck) {
+	// latency only exists when some message was paired, the check is still emitted without data
+	// so a firing alert resolves once traffic stops
+	latency := AlertCheck{Rule: "anomalyLatency", Type: "Anomalous Latency"}
+	if stats.MessageCount > 0 {
+		checks = append(checks, d.check(&d.latency, stats.AvgLatency, false, latency))
+	} else {
+		checks = append(checks, d.noData(&d.latency, latency))
+	}
+
+	checks = append(checks, d.check(&d.throughput, float64(stats.SentMesssages), true, AlertCheck{
+		Rule: "anomalyThroughput",
+		Type: "Anomalous Throughput",
+	}))
+
+	missingRatio := AlertCheck{Rule: "anomalyMissingRatio", Type: "Anomalous Missing Ratio"}
+	if total := stats.SentMesssages + stats.ReceivedMessages; total > 0 {
+		ratio := float64(stats.MissingReception+stats.MissingRelay) / float64(total)
+		checks = append(checks, d.check(&d.missingRatio, ratio, false, missingRatio))
+	} else {
+		checks = append(checks, d.noData(&d.missingRatio, missingRatio))
+	}
+
+	for i := range checks {
+		checks[i].Severity = SeverityWarning
+		checks[i].Pair = pair
+	}
+
+	return
+}
+
+// Only increases are anomalous unless bothWays is set
+func (d *AnomalyDetector) check(b *ewmaBaseline, x float64, bothWays bool, check AlertCheck) AlertCheck {
+	z := b.zscore(x)
+	warm := b.samples >= d.config.AnomalyWarmup
+
+	check.Firing = warm && (z > d.config.AnomalySensitivity || (bothWays && -z > d.config.AnomalySensitivity))
+	check.Value = fmt.Sprintf("%f (z-score %.2f)", x, z)
+	check.Threshold = fmt.Sprintf("%.2f ± %.2f std", b.mean, d.config.AnomalySensitivity)
+
+	b.update(x, d.config.AnomalyAlpha)
+
+	return check
+}
+
+// Never fires, and leaves the baseline as it is
+func (d *AnomalyDetector) noData(b *ewmaBaseline, check AlertCheck) AlertCheck {
+	check.Value = "no data"
+	check.Threshold = fmt.Sprintf("%.2f ± %.2f std", b.mean, d.config.AnomalySensitivity)
+
+	return check
+}
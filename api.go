@@ -2,11 +2,16 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"math/big"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 
 type BinStat struct {
@@ -15,6 +20,7 @@ type BinStat struct {
 	MissingPart      uint64
 	MissingReception uint64
 	MissingRelay     uint64
+	Timestamp        uint64
 }
 
 type BlockPrettyStat struct {
@@ -25,20 +31,23 @@ type BlockPrettyStat struct {
 	MissingRelay     uint64  `json:"missingRelay"`
 }
 
-func prettyBlockStat(bs BlockStat) (bps BlockPrettyStat) {
-	bps.MessageCount = bs.MessageCount
-	if bps.MessageCount == 0 {
-		bps.AvgLatency = 0
-	} else {
-		bps.AvgLatency = float64(bs.TotalLatency.Uint64()) / float64(bps.MessageCount)
-	}
-	bps.MissingPart = (bs.ReceivedMessages + bs.SentMesssages) - 2*(bs.MessageCount)
-	bps.MissingReception = bs.SentMesssages - bs.MessageCount
-	bps.MissingRelay = bs.ReceivedMessages - bs.MessageCount
+// A single block or bin on the `/all` response
+type BlockStatItem struct {
+	Block     uint64 `json:"block"`
+	Timestamp uint64 `json:"timestamp"`
+	BlockPrettyStat
+}
 
-	return
+type BlockStatPage struct {
+	Items      []BlockStatItem `json:"items"`
+	NextCursor *string         `json:"nextCursor"`
 }
 
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
 func binToPrettyStat(bs BinStat) (bps BlockPrettyStat) {
 	bps.MessageCount = bs.MessageCount
 	if bps.MessageCount == 0 {
@@ -47,6 +56,8 @@ func binToPrettyStat(bs BinStat) (bps BlockPrettyStat) {
 		bps.AvgLatency = float64(bs.TotalLatency.Uint64()) / float64(bps.MessageCount)
 	}
 	bps.MissingPart = bs.MissingPart
+	bps.MissingReception = bs.MissingReception
+	bps.MissingRelay = bs.MissingRelay
 
 	return
 }
@@ -55,68 +66,163 @@ func homeRoute(c echo.Context) error {
 	return c.String(http.StatusOK, "Ok!")
 }
 
-func (agg *Aggregator) All(c echo.Context) error {
-	prettyStats := make(map[uint64]BlockPrettyStat)
+// Parses an optional unsigned query param, keeping the default if not set
+func parseUintParam(c echo.Context, name string, value *uint64) error {
+	param := c.QueryParam(name)
+	if param == "" {
+		return nil
+	}
 
-	fromBlock := c.QueryParam("from")
-	var err error
-	from := uint64(0)
+	v, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid `%s` value", name)
+	}
 
-	if fromBlock != "" {
-		from, err = strconv.ParseUint(fromBlock, 10, 64)
+	*value = v
+	return nil
+}
 
-		if err != nil {
-			return c.String(http.StatusBadRequest, "Invalid `from` value")
-		}
+// Parses the `limit` query param of the paginated endpoints, defaultPageLimit if not set
+func parseLimitParam(c echo.Context) (int, error) {
+	limit := uint64(defaultPageLimit)
+	if err := parseUintParam(c, "limit", &limit); err != nil {
+		return 0, err
+	}
+	if limit == 0 || limit > maxPageLimit {
+		return 0, fmt.Errorf("`limit` must be between 1 and %d", maxPageLimit)
 	}
 
-	if c.QueryParam("bin") == "" {
-		for key, val := range agg.BlockStats {
-			if key >= from {
-				prettyStats[key] = prettyBlockStat(val)
-			}
+	return int(limit), nil
+}
+
+// Filters and pagination of the block stats, shared by `/all` and GraphQL
+type BlockStatQuery struct {
+	From     uint64
+	To       uint64
+	FromTime uint64
+	ToTime   uint64
+	Bin      uint64 // 0 returns single blocks
+	Desc     bool
+	Limit    uint64
+	Cursor   *uint64 // last key returned on the previous page
+}
+
+func NewBlockStatQuery() BlockStatQuery {
+	return BlockStatQuery{
+		To:     math.MaxUint64,
+		ToTime: math.MaxUint64,
+		Limit:  defaultPageLimit,
+	}
+}
 
+func (agg *Aggregator) BlockStatPage(q BlockStatQuery) BlockStatPage {
+	stats := make(map[uint64]BinStat)
+
+	agg.mu.RLock()
+	for key, val := range agg.BlockStats {
+		if key < q.From || key > q.To {
+			continue
+		}
+
+		// blocks with unknown timestamps are left out of timestamp range queries
+		if (q.FromTime != 0 || q.ToTime != math.MaxUint64) && (val.Timestamp == 0 || val.Timestamp < q.FromTime || val.Timestamp > q.ToTime) {
+			continue
+		}
+
+		bin := key
+		if q.Bin != 0 {
+			bin = key - (key % q.Bin)
 		}
 
-		return c.JSON(http.StatusOK, prettyStats)
+		binStats, ex := stats[bin]
+		if !ex {
+			binStats = BinStat{TotalLatency: big.NewInt(0)}
+		}
+
+		binStats.MessageCount += val.MessageCount
+		binStats.TotalLatency = big.NewInt(0).Add(binStats.TotalLatency, val.TotalLatency)
+		binStats.MissingPart += val.missingReception() + val.ReceivedMessages - val.MessageCount
+		binStats.MissingReception += val.missingReception()
+		binStats.MissingRelay += val.ReceivedMessages - val.MessageCount
+		if val.Timestamp != 0 && (binStats.Timestamp == 0 || val.Timestamp < binStats.Timestamp) {
+			binStats.Timestamp = val.Timestamp
+		}
+		stats[bin] = binStats
 	}
+	agg.mu.RUnlock()
 
-	binSize, err := strconv.ParseUint(c.QueryParam("bin"), 10, 64)
-	if binSize <= 0 {
-		err = fmt.Errorf("invalid bin size")
+	keys := make([]uint64, 0, len(stats))
+	for key := range stats {
+		if q.Cursor == nil || (!q.Desc && key > *q.Cursor) || (q.Desc && key < *q.Cursor) {
+			keys = append(keys, key)
+		}
 	}
 
-	if err != nil {
-		return c.String(http.StatusBadRequest, "Invalid `bin` value")
+	sort.Slice(keys, func(i, j int) bool {
+		if q.Desc {
+			return keys[i] > keys[j]
+		}
+		return keys[i] < keys[j]
+	})
+
+	page := BlockStatPage{Items: make([]BlockStatItem, 0)}
+
+	if uint64(len(keys)) > q.Limit {
+		keys = keys[:q.Limit]
+		next := strconv.FormatUint(keys[len(keys)-1], 10)
+		page.NextCursor = &next
 	}
 
-	aggStats := make(map[uint64]BinStat)
+	for _, key := range keys {
+		page.Items = append(page.Items, BlockStatItem{
+			Block:           key,
+			Timestamp:       stats[key].Timestamp,
+			BlockPrettyStat: binToPrettyStat(stats[key]),
+		})
+	}
 
-	for key, val := range agg.BlockStats {
-		if key >= from {
-			bin := key - (key % binSize)
-			_, ex := aggStats[bin]
-
-			if !ex {
-				aggStats[bin] = BinStat{0, big.NewInt(0), 0, 0, 0}
-			}
-
-			newStats := BinStat{}
-			newStats.MessageCount = aggStats[bin].MessageCount + val.MessageCount
-			newStats.TotalLatency = big.NewInt(0).Add(aggStats[bin].TotalLatency, val.TotalLatency)
-			newStats.MissingPart = aggStats[bin].MissingPart + (val.ReceivedMessages + val.SentMesssages) - 2*(val.MessageCount)
-			newStats.MissingReception = aggStats[bin].MissingReception + val.SentMesssages - val.MessageCount
-			newStats.MissingRelay = aggStats[bin].MissingRelay + val.ReceivedMessages - val.MessageCount
-			aggStats[bin] = newStats
+	return page
+}
+
+func (agg *Aggregator) All(c echo.Context) error {
+	q := NewBlockStatQuery()
+
+	for name, value := range map[string]*uint64{
+		"from":     &q.From,
+		"to":       &q.To,
+		"fromTime": &q.FromTime,
+		"toTime":   &q.ToTime,
+		"limit":    &q.Limit,
+		"bin":      &q.Bin,
+	} {
+		if err := parseUintParam(c, name, value); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
 		}
 	}
 
-	for key, val := range aggStats {
-		prettyStats[key] = binToPrettyStat(val)
+	if c.QueryParam("bin") != "" && q.Bin == 0 {
+		return c.String(http.StatusBadRequest, "Invalid `bin` value")
+	}
+
+	if q.Limit == 0 || q.Limit > maxPageLimit {
+		return c.String(http.StatusBadRequest, fmt.Sprintf("`limit` must be between 1 and %d", maxPageLimit))
 	}
 
-	return c.JSON(http.StatusOK, prettyStats)
+	order := c.QueryParam("order")
+	if order != "" && order != "asc" && order != "desc" {
+		return c.String(http.StatusBadRequest, "Invalid `order` value")
+	}
+	q.Desc = order == "desc"
 
+	if c.QueryParam("cursor") != "" {
+		var v uint64
+		if err := parseUintParam(c, "cursor", &v); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		q.Cursor = &v
+	}
+
+	return c.JSON(http.StatusOK, agg.BlockStatPage(q))
 }
 
 func (agg *Aggregator) LatestBlockRoute(c echo.Context) error {
@@ -141,14 +247,349 @@ func (agg *Aggregator) LatestBlockRoute(c echo.Context) error {
 	return c.JSON(http.StatusOK, stats)
 }
 
-func StartApi(config *Config, agg *Aggregator) {
+// Accepts either an RFC 3339 date or a unix timestamp in seconds
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+func activeAlertsRoute(c echo.Context) error {
+	return c.JSON(http.StatusOK, alertManager.Active())
+}
+
+func alertHistoryRoute(c echo.Context) error {
+	var err error
+	q := AlertHistoryQuery{
+		Rule: c.QueryParam("rule"),
+		Pair: c.QueryParam("pair"),
+	}
+
+	if q.From, err = parseTimeParam(c.QueryParam("from")); err != nil {
+		return c.String(http.StatusBadRequest, "Invalid `from` value")
+	}
+
+	if q.To, err = parseTimeParam(c.QueryParam("to")); err != nil {
+		return c.String(http.StatusBadRequest, "Invalid `to` value")
+	}
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if q.Limit, err = strconv.Atoi(limitParam); err != nil || q.Limit < 0 {
+			return c.String(http.StatusBadRequest, "Invalid `limit` value")
+		}
+	}
+
+	return c.JSON(http.StatusOK, alertManager.History(q))
+}
+
+func alertDeliveriesRoute(c echo.Context) error {
+	status := DeliveryStatus(c.QueryParam("status"))
+
+	switch status {
+	case "", DeliveryPending, DeliveryDelivered, DeliveryFailed:
+	default:
+		return c.String(http.StatusBadRequest, "Invalid `status` value")
+	}
+
+	return c.JSON(http.StatusOK, alertOutbox.Deliveries(status))
+}
+
+func silencesRoute(c echo.Context) error {
+	activeOnly := c.QueryParam("active") == "true"
+
+	return c.JSON(http.StatusOK, alertManager.Silences(activeOnly))
+}
+
+func createSilenceRoute(c echo.Context) error {
+	var req struct {
+		Silence
+		Duration string `json:"duration"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.String(http.StatusBadRequest, "Invalid silence body")
+	}
+
+	silence := req.Silence
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			return c.String(http.StatusBadRequest, "Invalid `duration` value")
+		}
+
+		if silence.StartsAt.IsZero() {
+			silence.StartsAt = time.Now().UTC()
+		}
+		silence.EndsAt = silence.StartsAt.Add(duration)
+	}
+
+	silence, err := alertManager.AddSilence(silence)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, silence)
+}
+
+func expireSilenceRoute(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Invalid silence id")
+	}
+
+	silence, err := alertManager.ExpireSilence(id)
+	if err != nil {
+		return c.String(http.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, silence)
+}
+
+func acknowledgeAlertRoute(c echo.Context) error {
+	var req struct {
+		Rule string `json:"rule"`
+		Pair string `json:"pair"`
+	}
+
+	if err := c.Bind(&req); err != nil || req.Rule == "" || req.Pair == "" {
+		return c.String(http.StatusBadRequest, "`rule` and `pair` are required")
+	}
+
+	alert, err := alertManager.Acknowledge(req.Rule, req.Pair)
+	if err != nil {
+		return c.String(http.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, alert)
+}
+
+func (agg *Aggregator) SLORoute(c echo.Context) error {
+	return c.JSON(http.StatusOK, agg.slo.Report(agg.Name()))
+}
+
+func (agg *Aggregator) MessagesRoute(c echo.Context) error {
+	q := MessageQuery{Status: MessageStatus(c.QueryParam("status"))}
+
+	switch q.Status {
+	case "", MessagePending, MessageRelayed, MessageExpired, MessageReconciled:
+	default:
+		return c.String(http.StatusBadRequest, "Invalid `status` value")
+	}
+
+	var minAge uint64
+	if err := parseUintParam(c, "minAge", &minAge); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	limit, err := parseLimitParam(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	q.MinAge = time.Duration(minAge) * time.Second
+	q.Limit = limit
+
+	return c.JSON(http.StatusOK, agg.Messages(q))
+}
+
+type LatencyReport struct {
+	Pair    string          `json:"pair"`
+	Bucket  uint64          `json:"bucket"` // in seconds
+	Buckets []LatencyBucket `json:"buckets"`
+}
+
+func (agg *Aggregator) LatencyRoute(c echo.Context) error {
+	bucket := uint64(300)
+	if err := parseUintParam(c, "bucket", &bucket); err != nil || bucket == 0 {
+		return c.String(http.StatusBadRequest, "Invalid `bucket` value")
+	}
+
+	since, err := parseTimeParam(c.QueryParam("since"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Invalid `since` value")
+	}
+
+	return c.JSON(http.StatusOK, LatencyReport{
+		Pair:    agg.Name(),
+		Bucket:  bucket,
+		Buckets: agg.LatencyPercentiles(time.Duration(bucket)*time.Second, since),
+	})
+}
+
+func (agg *Aggregator) BreakdownRoute(c echo.Context) error {
+	limit, err := parseLimitParam(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	report, err := agg.Breakdown(c.QueryParam("by"), limit)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "`by` must be `target`, `sender`, `token` or `recipient`")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func (agg *Aggregator) RelayersRoute(c echo.Context) error {
+	limit, err := parseLimitParam(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, agg.Relayers(limit))
+}
+
+func (agg *Aggregator) CostsRoute(c echo.Context) error {
+	bucket := uint64(3600)
+	if err := parseUintParam(c, "bucket", &bucket); err != nil || bucket == 0 {
+		return c.String(http.StatusBadRequest, "Invalid `bucket` value")
+	}
+
+	since, err := parseTimeParam(c.QueryParam("since"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Invalid `since` value")
+	}
+
+	return c.JSON(http.StatusOK, agg.Costs(time.Duration(bucket)*time.Second, since))
+}
+
+func (agg *Aggregator) RelayCostsRoute(c echo.Context) error {
+	limit, err := parseLimitParam(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	var relayer *common.Address
+	if value := c.QueryParam("relayer"); value != "" {
+		if !common.IsHexAddress(value) {
+			return c.String(http.StatusBadRequest, "Invalid `relayer` value")
+		}
+		address := common.HexToAddress(value)
+		relayer = &address
+	}
+
+	return c.JSON(http.StatusOK, agg.RelayCosts(relayer, limit))
+}
+
+func (agg *Aggregator) FailuresRoute(c echo.Context) error {
+	limit, err := parseLimitParam(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, agg.Failures(c.QueryParam("reason"), limit))
+}
+
+func (agg *Aggregator) ReconciliationRoute(c echo.Context) error {
+	limit, err := parseLimitParam(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, agg.Reconciliation(limit))
+}
+
+func (agg *Aggregator) NoncesRoute(c echo.Context) error {
+	limit, err := parseLimitParam(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, agg.Nonces(limit))
+}
+
+func (agg *Aggregator) InflightRoute(c echo.Context) error {
+	return c.JSON(http.StatusOK, agg.Inflight())
+}
+
+func (agg *Aggregator) OriginsRoute(c echo.Context) error {
+	limit, err := parseLimitParam(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, agg.Origins(limit))
+}
+
+func (agg *Aggregator) SupervisorRoute(c echo.Context) error {
+	limit, err := parseLimitParam(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, agg.Supervisor(limit))
+}
+
+func StartApi(config *Config, m *Monitor) {
 	e := echo.New()
 	e.HideBanner = true
-	e.Debug = true
+	e.Debug = config.APIDebug
 
-	e.GET("/", homeRoute)
-	e.GET("/all", agg.All)
-	e.GET("/latest", agg.LatestBlockRoute)
+	if config.APIRateLimit > 0 {
+		e.Use(rateLimiter(config))
+	}
+
+	if len(config.APICorsOrigins) > 0 {
+		e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+			AllowOrigins: config.APICorsOrigins,
+			AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+			AllowHeaders: []string{echo.HeaderAuthorization, echo.HeaderContentType, "X-API-Key"},
+		}))
+	}
+	wsUpgrader.CheckOrigin = websocketOriginChecker(config.APICorsOrigins)
 
-	e.Logger.Fatal(e.Start(fmt.Sprintf(":%d", config.APIPort)))
+	warnWithoutAPIKeys(config)
+	read := requireScope(config, ScopeRead)
+	admin := requireScope(config, ScopeAdmin)
+
+	e.GET("/", homeRoute)
+	e.GET("/healthz", healthzRoute)
+	e.GET("/readyz", m.ReadyzRoute)
+	e.GET("/all", m.route((*Aggregator).All), read)
+	e.GET("/latest", m.route((*Aggregator).LatestBlockRoute), read)
+	e.GET("/slo", m.route((*Aggregator).SLORoute), read)
+	e.GET("/messages", m.route((*Aggregator).MessagesRoute), read)
+	e.GET("/latency", m.route((*Aggregator).LatencyRoute), read)
+	e.GET("/breakdown", m.route((*Aggregator).BreakdownRoute), read)
+	e.GET("/relayers", m.route((*Aggregator).RelayersRoute), read)
+	e.GET("/costs", m.route((*Aggregator).CostsRoute), read)
+	e.GET("/costs/transactions", m.route((*Aggregator).RelayCostsRoute), read)
+	e.GET("/failures", m.route((*Aggregator).FailuresRoute), read)
+	e.GET("/reconciliation", m.route((*Aggregator).ReconciliationRoute), read)
+	e.GET("/nonces", m.route((*Aggregator).NoncesRoute), read)
+	e.GET("/inflight", m.route((*Aggregator).InflightRoute), read)
+	e.GET("/origins", m.route((*Aggregator).OriginsRoute), read)
+	e.GET("/supervisor", m.route((*Aggregator).SupervisorRoute), read)
+	e.GET("/pairs", m.PairsRoute, read)
+	e.GET("/metrics", metricsRoute(m.Pairs), read)
+
+	graphQL := graphQLRoute(NewGraphQLSchema(m.Pairs))
+	e.GET("/graphql", graphQL, read)
+	e.POST("/graphql", graphQL, read)
+
+	e.GET("/stream", streamRoute, read)
+	e.GET("/stream/ws", streamWebSocketRoute, read)
+	e.GET("/alerts", activeAlertsRoute, read)
+	e.GET("/alerts/history", alertHistoryRoute, read)
+	e.GET("/alerts/deliveries", alertDeliveriesRoute, read)
+	e.POST("/alerts/ack", acknowledgeAlertRoute, admin)
+	e.GET("/silences", silencesRoute, read)
+	e.POST("/silences", createSilenceRoute, admin)
+	e.DELETE("/silences/:id", expireSilenceRoute, admin)
+	e.GET("/openapi.json", openAPIRoute)
+	e.GET("/ui", uiRedirectRoute)
+	e.GET("/ui/*", uiRoute)
+
+	checkOpenAPIRoutes(e)
+
+	address := fmt.Sprintf("%s:%d", config.APIHost, config.APIPort)
+	if config.APITLSCertFile != "" {
+		e.Logger.Fatal(e.StartTLS(address, config.APITLSCertFile, config.APITLSKeyFile))
+	}
+	e.Logger.Fatal(e.Start(address))
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+const (
+	ScopeRead  = "read"  // query endpoints and streams
+	ScopeAdmin = "admin" // everything, including silences and acknowledgements
+)
+
+type APIKey struct {
+	Name  string `json:"name"` // identifies the client in logs and rate limits
+	Key   string `json:"key"`
+	Scope string `json:"scope"`
+}
+
+func (k APIKey) allows(scope string) bool {
+	return k.Scope == ScopeAdmin || k.Scope == scope
+}
+
+func validateAPIKeys(keys []APIKey) error {
+	names := make(map[string]bool)
+
+	for i, k := range keys {
+		if k.Name == "" {
+			return fmt.Errorf("apiKeys[%d]: name is required", i)
+		}
+		if names[k.Name] {
+			return fmt.Errorf("apiKeys[%d]: duplicate name %q", i, k.Name)
+		}
+		names[k.Name] = true
+
+		if len(k.Key) < 16 {
+			return fmt.Errorf("apiKeys[%d]: key must be at least 16 characters long", i)
+		}
+		if k.Scope != ScopeRead && k.Scope != ScopeAdmin {
+			return fmt.Errorf("apiKeys[%d]: scope must be %q or %q", i, ScopeRead, ScopeAdmin)
+		}
+	}
+
+	return nil
+}
+
+// Reads the key from the `Authorization: Bearer` or `X-API-Key` headers, or from the `apiKey`
+// query param since browsers can't set headers on EventSource and WebSocket connections
+func requestAPIKey(c echo.Context) string {
+	req := c.Request()
+
+	if auth := req.Header.Get(echo.HeaderAuthorization); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if key := req.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	return c.QueryParam("apiKey")
+}
+
+func findAPIKey(keys []APIKey, key string) *APIKey {
+	if key == "" {
+		return nil
+	}
+
+	for i := range keys {
+		if subtle.ConstantTimeCompare([]byte(keys[i].Key), []byte(key)) == 1 {
+			return &keys[i]
+		}
+	}
+
+	return nil
+}
+
+// Whether the API only listens on the loopback interface
+func loopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Without configured keys the API is open, as before authentication existed, apart from the
+// admin endpoints when the API is reachable from other hosts
+func requireScope(config *Config, scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if len(config.APIKeys) == 0 {
+				if scope == ScopeAdmin && !loopbackHost(config.APIHost) {
+					return c.String(http.StatusForbidden, "The `admin` scope requires `apiKeys` unless `apiHost` is a loopback address")
+				}
+				return next(c)
+			}
+
+			key := findAPIKey(config.APIKeys, requestAPIKey(c))
+			if key == nil {
+				return c.String(http.StatusUnauthorized, "Missing or invalid API key")
+			}
+			if !key.allows(scope) {
+				return c.String(http.StatusForbidden, fmt.Sprintf("API key lacks the `%s` scope", scope))
+			}
+
+			c.Set("apiKey", key.Name)
+			return next(c)
+		}
+	}
+}
+
+// Whether the request may see what the scope guards, for endpoints that stay open to anyone
+// but only detail their answer to authorized clients
+func allowsScope(config *Config, c echo.Context, scope string) bool {
+	if len(config.APIKeys) == 0 {
+		return scope != ScopeAdmin || loopbackHost(config.APIHost)
+	}
+
+	key := findAPIKey(config.APIKeys, requestAPIKey(c))
+	return key != nil && key.allows(scope)
+}
+
+func warnWithoutAPIKeys(config *Config) {
+	if len(config.APIKeys) > 0 {
+		return
+	}
+
+	if loopbackHost(config.APIHost) {
+		log.Printf("api: no apiKeys configured, every endpoint is open to local clients")
+	} else {
+		log.Printf("api: no apiKeys configured, read endpoints are open to anyone reaching %q and admin endpoints are disabled", config.APIHost)
+	}
+}
+
+// Clients are identified by their API key when they send a valid one, else by IP
+func rateLimiter(config *Config) echo.MiddlewareFunc {
+	store := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Limit(config.APIRateLimit),
+		Burst:     config.APIRateBurst,
+		ExpiresIn: 3 * time.Minute,
+	})
+
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Skipper: func(c echo.Context) bool {
+			path := c.Path()
+			return path == "/healthz" || path == "/readyz"
+		},
+		IdentifierExtractor: func(c echo.Context) (string, error) {
+			if key := findAPIKey(config.APIKeys, requestAPIKey(c)); key != nil {
+				return "key:" + key.Name, nil
+			}
+			return "ip:" + c.RealIP(), nil
+		},
+		Store: store,
+		DenyHandler: func(c echo.Context, identifier string, err error) error {
+			c.Response().Header().Set("Retry-After", "1")
+			return c.String(http.StatusTooManyRequests, "Rate limit exceeded")
+		},
+	})
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebSocket upgrades don't go through CORS, so the allow-list is enforced on the handshake.
+// Requests without an origin (non browser clients) and same origin requests are always allowed
+func websocketOriginChecker(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || originAllowed(allowed, origin) {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
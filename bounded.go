@@ -0,0 +1,97 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// A stat of a bounded tracker
+type boundedStat[S any] interface {
+	*S
+	key() string
+	// events ranking the stat, and the time of the last one
+	activity() (events uint64, lastSeen time.Time)
+	// folds another stat in, for `other`
+	add(o *S)
+}
+
+// Stats keyed by address, bounded to the `size` busiest ones. When full, the least active key
+// is folded into `other` to make room, so short lived keys can't grow memory unbounded.
+// Breakdowns, relayers and origins are all tracked this way
+type boundedStats[S any, P boundedStat[S]] struct {
+	stats   map[common.Address]*S
+	other   S
+	size    int
+	newStat func(key common.Address) S
+}
+
+func newBoundedStats[S any, P boundedStat[S]](size int, other S, newStat func(key common.Address) S) boundedStats[S, P] {
+	return boundedStats[S, P]{
+		stats:   make(map[common.Address]*S),
+		other:   other,
+		size:    size,
+		newStat: newStat,
+	}
+}
+
+// Whether a ranks before b: more events, then the most recent last event, then the lowest key
+func ranksBefore[S any, P boundedStat[S]](a, b *S) bool {
+	aEvents, aSeen := P(a).activity()
+	bEvents, bSeen := P(b).activity()
+	if aEvents != bEvents {
+		return aEvents > bEvents
+	}
+	if !aSeen.Equal(bSeen) {
+		return aSeen.After(bSeen)
+	}
+	return P(a).key() < P(b).key()
+}
+
+// Returns the stat of the key, creating it when asked. Otherwise the events of keys that were
+// evicted, or never tracked, are accounted on `other`
+func (b *boundedStats[S, P]) get(key common.Address, create bool) *S {
+	if stat, ok := b.stats[key]; ok {
+		return stat
+	}
+	if !create {
+		return &b.other
+	}
+
+	if len(b.stats) >= b.size {
+		var evicted common.Address
+		var least *S
+		for k, stat := range b.stats {
+			if least == nil || ranksBefore[S, P](least, stat) {
+				evicted, least = k, stat
+			}
+		}
+		P(&b.other).add(least)
+		delete(b.stats, evicted)
+	}
+
+	stat := b.newStat(key)
+	b.stats[key] = &stat
+	return &stat
+}
+
+// Returns the `limit` busiest stats, and the sum of the rest
+func (b *boundedStats[S, P]) top(limit int) (items []S, other S) {
+	items = make([]S, 0, len(b.stats))
+	for _, stat := range b.stats {
+		items = append(items, *stat)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return ranksBefore[S, P](&items[i], &items[j]) })
+
+	other = b.other
+	if limit > 0 && len(items) > limit {
+		for i := range items[limit:] {
+			P(&other).add(&items[limit+i])
+		}
+		items = items[:limit]
+	}
+
+	return
+}
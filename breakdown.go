@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	BreakdownByTarget    = "target"
+	BreakdownBySender    = "sender"
+	BreakdownByToken     = "token"     // of the decoded payloads
+	BreakdownByRecipient = "recipient" // of the decoded payloads
+)
+
+// Message counts of a single target contract, sending address, token or recipient, since the monitor started
+type BreakdownStat struct {
+	Key          string    `json:"key"` // address, or "other" for the keys out of the top N
+	Sent         uint64    `json:"sent"`
+	Relayed      uint64    `json:"relayed"`
+	Expired      uint64    `json:"expired"`
+	Reconciled   uint64    `json:"reconciled"` // relayed, but the relay log was missed
+	Pending      uint64    `json:"pending"`
+	TotalLatency uint64    `json:"totalLatency"` // in seconds, over the relayed messages
+	AvgLatency   float64   `json:"avgLatency"`
+	MaxLatency   uint64    `json:"maxLatency"`
+	LastSeen     time.Time `json:"lastSeen"`         // last message sent
+	Amount       *big.Int  `json:"amount,omitempty"` // sum of the decoded amounts, by token only and not on "other"
+}
+
+func (s *BreakdownStat) key() string { return s.Key }
+
+func (s *BreakdownStat) activity() (uint64, time.Time) { return s.Sent, s.LastSeen }
+
+// Amounts aren't folded, `other` mixes tokens
+func (s *BreakdownStat) add(o *BreakdownStat) {
+	s.Sent += o.Sent
+	s.Relayed += o.Relayed
+	s.Expired += o.Expired
+	s.Reconciled += o.Reconciled
+	s.Pending += o.Pending
+	s.TotalLatency += o.TotalLatency
+	s.MaxLatency = max(s.MaxLatency, o.MaxLatency)
+	if o.LastSeen.After(s.LastSeen) {
+		s.LastSeen = o.LastSeen
+	}
+}
+
+// The amount is replaced rather than mutated, since copies of the stat share it
+func (s *BreakdownStat) addAmount(amount *big.Int) {
+	if amount == nil {
+		return
+	}
+	if s.Amount == nil {
+		s.Amount = new(big.Int).Set(amount)
+		return
+	}
+	s.Amount = new(big.Int).Add(s.Amount, amount)
+}
+
+// Message counts by address, bounded to the `size` busiest ones
+type Breakdown struct {
+	boundedStats[BreakdownStat, *BreakdownStat]
+}
+
+func NewBreakdown(size int) *Breakdown {
+	return &Breakdown{newBoundedStats[BreakdownStat, *BreakdownStat](size, BreakdownStat{Key: "other"}, func(key common.Address) BreakdownStat {
+		return BreakdownStat{Key: key.Hex()}
+	})}
+}
+
+func (b *Breakdown) sent(key common.Address, at time.Time, amount *big.Int) {
+	stat := b.get(key, true)
+	stat.Sent++
+	stat.Pending++
+	stat.LastSeen = at
+	stat.addAmount(amount)
+}
+
+func (s *BreakdownStat) counter(status MessageStatus) *uint64 {
+	switch status {
+	case MessagePending:
+		return &s.Pending
+	case MessageRelayed:
+		return &s.Relayed
+	case MessageExpired:
+		return &s.Expired
+	default:
+		return &s.Reconciled
+	}
+}
+
+// Moves a message from its previous status to its current one. The previous counts are
+// saturated since the key may have been evicted and added back since sending
+func (b *Breakdown) transition(key common.Address, record *MessageRecord, previous MessageStatus) {
+	stat := b.get(key, false)
+	if count := stat.counter(previous); *count > 0 {
+		*count--
+	}
+	*stat.counter(record.Status)++
+
+	if record.Status == MessageRelayed {
+		stat.TotalLatency += *record.Latency
+		stat.MaxLatency = max(stat.MaxLatency, *record.Latency)
+	}
+}
+
+// Returns the `limit` keys with the most sent messages, and the sum of the rest
+func (b *Breakdown) Top(limit int) (items []BreakdownStat, other BreakdownStat) {
+	items, other = b.top(limit)
+	for i := range items {
+		items[i].AvgLatency = avgLatency(items[i])
+	}
+	other.AvgLatency = avgLatency(other)
+
+	return
+}
+
+func avgLatency(s BreakdownStat) float64 {
+	if s.Relayed == 0 {
+		return 0
+	}
+	return float64(s.TotalLatency) / float64(s.Relayed)
+}
+
+// Breakdowns of a pair's messages by target contract and by sending address, and of the
+// decoded payloads by token and by recipient
+type Breakdowns struct {
+	byTarget    *Breakdown
+	bySender    *Breakdown
+	byToken     *Breakdown
+	byRecipient *Breakdown
+}
+
+func NewBreakdowns(size int) *Breakdowns {
+	return &Breakdowns{
+		byTarget:    NewBreakdown(size),
+		bySender:    NewBreakdown(size),
+		byToken:     NewBreakdown(size),
+		byRecipient: NewBreakdown(size),
+	}
+}
+
+func (b *Breakdowns) sent(record *MessageRecord) {
+	b.byTarget.sent(record.Target, record.SentAt, nil)
+	b.bySender.sent(record.Sender, record.SentAt, nil)
+
+	if p := record.Payload; p != nil {
+		if p.Token != nil {
+			b.byToken.sent(*p.Token, record.SentAt, p.Amount)
+		}
+		if p.Recipient != nil {
+			b.byRecipient.sent(*p.Recipient, record.SentAt, nil)
+		}
+	}
+}
+
+func (b *Breakdowns) transition(record *MessageRecord, previous MessageStatus) {
+	b.byTarget.transition(record.Target, record, previous)
+	b.bySender.transition(record.Sender, record, previous)
+
+	if p := record.Payload; p != nil {
+		if p.Token != nil {
+			b.byToken.transition(*p.Token, record, previous)
+		}
+		if p.Recipient != nil {
+			b.byRecipient.transition(*p.Recipient, record, previous)
+		}
+	}
+}
+
+type BreakdownReport struct {
+	Pair  string          `json:"pair"`
+	By    string          `json:"by"`
+	Items []BreakdownStat `json:"items"`
+	Other BreakdownStat   `json:"other"`
+}
+
+func (agg *Aggregator) Breakdown(by string, limit int) (BreakdownReport, error) {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	var b *Breakdown
+	switch by {
+	case BreakdownByTarget:
+		b = agg.breakdowns.byTarget
+	case BreakdownBySender:
+		b = agg.breakdowns.bySender
+	case BreakdownByToken:
+		b = agg.breakdowns.byToken
+	case BreakdownByRecipient:
+		b = agg.breakdowns.byRecipient
+	default:
+		return BreakdownReport{}, fmt.Errorf("invalid breakdown %q", by)
+	}
+
+	items, other := b.Top(limit)
+
+	return BreakdownReport{Pair: agg.Name(), By: by, Items: items, Other: other}, nil
+}
+
+const (
+	RuleMetricAvgLatency       = "avgLatency"
+	RuleMetricMaxLatency       = "maxLatency"
+	RuleMetricMissingReception = "missingReception"
+	RuleMetricValueTransferred = "valueTransferred" // sum of the decoded amounts of a token
+	RuleMetricStuckValue       = "stuckValue"       // same, over the messages pending for at least minAge
+)
+
+// Alert rule over the messages of the latest blocks matching a target, sender and/or token
+type AlertRule struct {
+	Name      string          `json:"name"`
+	Metric    string          `json:"metric"`
+	Threshold float64         `json:"threshold"`
+	Severity  string          `json:"severity"`
+	Target    *common.Address `json:"target"`
+	Sender    *common.Address `json:"sender"`
+	Token     *common.Address `json:"token"`  // of the decoded payload, required by the value metrics
+	MinAge    uint64          `json:"minAge"` // in seconds, for stuckValue
+}
+
+func (r AlertRule) matches(record *MessageRecord) bool {
+	return (r.Target == nil || *r.Target == record.Target) &&
+		(r.Sender == nil || *r.Sender == record.Sender) &&
+		(r.Token == nil || (record.Payload != nil && record.Payload.Token != nil && *r.Token == *record.Payload.Token))
+}
+
+var builtinRules = map[string]bool{
+	"avgLatency":          true,
+	"missingReception":    true,
+	"missingRelay":        true,
+	"noMessages":          true,
+	"fetchStalled":        true,
+	"headStalled":         true,
+	"anomalyLatency":      true,
+	"anomalyThroughput":   true,
+	"anomalyMissingRatio": true,
+	"relayCostSpike":      true,
+	"failedExecutions":    true,
+	"missedRelays":        true,
+	"nonceGap":            true,
+	"relayReplay":         true,
+	"inflightValue":       true,
+	"supervisorMismatch":  true,
+	"crossSafeLag":        true,
+}
+
+func validateAlertRules(rules []AlertRule) error {
+	names := make(map[string]bool)
+
+	for i := range rules {
+		rule := &rules[i]
+
+		if rule.Name == "" {
+			return fmt.Errorf("alertRules[%d]: name is required", i)
+		}
+		if names[rule.Name] || builtinRules[rule.Name] {
+			return fmt.Errorf("alertRules[%d]: name %q is already used", i, rule.Name)
+		}
+		names[rule.Name] = true
+
+		switch rule.Metric {
+		case RuleMetricAvgLatency, RuleMetricMaxLatency, RuleMetricMissingReception:
+		case RuleMetricValueTransferred, RuleMetricStuckValue:
+			// amounts of different tokens don't add up
+			if rule.Token == nil {
+				return fmt.Errorf("alertRules[%d]: token is required by %q", i, rule.Metric)
+			}
+		default:
+			return fmt.Errorf("alertRules[%d]: metric must be one of %q, %q, %q, %q or %q", i, RuleMetricAvgLatency, RuleMetricMaxLatency, RuleMetricMissingReception, RuleMetricValueTransferred, RuleMetricStuckValue)
+		}
+
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("alertRules[%d]: threshold must be positive", i)
+		}
+
+		switch rule.Severity {
+		case "":
+			rule.Severity = SeverityWarning
+		case SeverityWarning, SeverityCritical:
+		default:
+			return fmt.Errorf("alertRules[%d]: severity must be %q or %q", i, SeverityWarning, SeverityCritical)
+		}
+	}
+
+	return nil
+}
+
+// Evaluates the configured alert rules over the messages sent in the latest aggregated blocks,
+// or over all the pending messages for stuckValue
+func (agg *Aggregator) RuleChecks() (checks []AlertCheck) {
+	rules := agg.config.AlertRules
+	if len(rules) == 0 {
+		return nil
+	}
+
+	type ruleStats struct {
+		sent, relayed, reconciled, totalLatency, maxLatency uint64
+		value                                               float64 // decoded amounts
+	}
+	stats := make([]ruleStats, len(rules))
+
+	agg.mu.RLock()
+	from := int64(*agg.LatestBlock) - int64(agg.config.AggregateBlockAmount)
+
+	observe := func(record *MessageRecord) {
+		inWindow := int64(record.SentBlock) >= from
+
+		var amount float64
+		if record.Payload != nil && record.Payload.Amount != nil {
+			amount = weiFloat(record.Payload.Amount)
+		}
+
+		for i, rule := range rules {
+			if !rule.matches(record) {
+				continue
+			}
+
+			if rule.Metric == RuleMetricStuckValue {
+				// pending messages are expired after 2*aggregateBlockAmount blocks, long before
+				// they are stuck, so the expired ones not reconciled since count too
+				stuck := record.Status == MessagePending || record.Status == MessageExpired
+				if stuck && time.Since(record.SentAt) >= time.Duration(rule.MinAge)*time.Second {
+					stats[i].value += amount
+				}
+				continue
+			}
+			if !inWindow {
+				continue
+			}
+
+			stats[i].sent++
+			stats[i].value += amount
+			if record.Status == MessageReconciled {
+				stats[i].reconciled++
+			}
+			if record.Status == MessageRelayed {
+				stats[i].relayed++
+				stats[i].totalLatency += *record.Latency
+				stats[i].maxLatency = max(stats[i].maxLatency, *record.Latency)
+			}
+		}
+	}
+
+	for _, record := range agg.messages.pending {
+		observe(record)
+	}
+	for _, record := range agg.messages.finished {
+		observe(record)
+	}
+	agg.mu.RUnlock()
+
+	for i, rule := range rules {
+		var value float64
+		switch rule.Metric {
+		case RuleMetricAvgLatency:
+			if stats[i].relayed > 0 {
+				value = float64(stats[i].totalLatency) / float64(stats[i].relayed)
+			}
+		case RuleMetricMaxLatency:
+			value = float64(stats[i].maxLatency)
+		case RuleMetricMissingReception:
+			value = float64(stats[i].sent - stats[i].relayed - stats[i].reconciled)
+		case RuleMetricValueTransferred, RuleMetricStuckValue:
+			value = stats[i].value
+		}
+
+		checks = append(checks, AlertCheck{
+			Rule:      rule.Name,
+			Type:      rule.description(),
+			Severity:  rule.Severity,
+			Pair:      agg.Name(),
+			Firing:    value > rule.Threshold,
+			Value:     fmt.Sprintf("%g", value),
+			Threshold: fmt.Sprintf("%g", rule.Threshold),
+		})
+	}
+
+	return
+}
+
+func (r AlertRule) description() string {
+	names := map[string]string{
+		RuleMetricAvgLatency:       "Average Latency",
+		RuleMetricMaxLatency:       "Max Latency",
+		RuleMetricMissingReception: "Missing Reception",
+		RuleMetricValueTransferred: "Value Transferred",
+		RuleMetricStuckValue:       "Stuck Value",
+	}
+
+	desc := names[r.Metric]
+	if r.Target != nil {
+		desc += " to " + r.Target.Hex()
+	}
+	if r.Sender != nil {
+		desc += " from " + r.Sender.Hex()
+	}
+	if r.Token != nil {
+		desc += " of token " + r.Token.Hex()
+	}
+
+	return desc
+}
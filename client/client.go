@@ -0,0 +1,603 @@
+// Package client is a Go client for the optimism-interop-monitoring API.
+//
+// The endpoints and types mirror the `openapi.json` document served by the monitor.
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type Client struct {
+	BaseURL    string // e.g. http://localhost:8800
+	HTTPClient *http.Client
+	Header     http.Header // added to every request
+	Pair       string      // pair of the pair scoped endpoints, required when several pairs are monitored
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		Header:     make(http.Header),
+	}
+}
+
+// Authenticates every request with the given key
+func (c *Client) SetAPIKey(key string) {
+	c.Header.Set("Authorization", "Bearer "+key)
+}
+
+// Endpoints reporting on a single pair, selected with the `pair` query param
+var pairPaths = map[string]bool{
+	"/readyz": true, "/all": true, "/latest": true, "/slo": true, "/messages": true, "/latency": true,
+	"/breakdown": true, "/relayers": true, "/costs": true, "/costs/transactions": true, "/failures": true,
+	"/reconciliation": true, "/nonces": true, "/inflight": true, "/origins": true, "/supervisor": true,
+}
+
+// Returned when the API answers with a non 2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.StatusCode, e.Message)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	u := c.BaseURL + path
+	if c.Pair != "" && pairPaths[path] && query.Get("pair") == "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("pair", c.Pair)
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range c.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return decodeResponse(res, out)
+}
+
+func decodeResponse(res *http.Response, out interface{}) error {
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return &APIError{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func setUint(q url.Values, name string, v *uint64) {
+	if v != nil {
+		q.Set(name, strconv.FormatUint(*v, 10))
+	}
+}
+
+func setTime(q url.Values, name string, t time.Time) {
+	if !t.IsZero() {
+		q.Set(name, t.UTC().Format(time.RFC3339))
+	}
+}
+
+// Liveness of the monitor process
+func (c *Client) Healthz(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/healthz", nil, nil, nil)
+}
+
+// Readiness of the pipeline, a not ready monitor still returns its report along with an *APIError.
+// The problems and chains are only reported to keys with the `read` scope
+func (c *Client) Readyz(ctx context.Context) (*Readiness, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/readyz", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var readiness Readiness
+	if res.StatusCode == http.StatusServiceUnavailable {
+		if err := json.NewDecoder(res.Body).Decode(&readiness); err != nil {
+			return nil, err
+		}
+		message := strings.Join(readiness.Problems, "; ")
+		if message == "" {
+			message = "not ready"
+		}
+		return &readiness, &APIError{StatusCode: res.StatusCode, Message: message}
+	}
+
+	if err := decodeResponse(res, &readiness); err != nil {
+		return nil, err
+	}
+	return &readiness, nil
+}
+
+// Parameters of `/all`, nil values are left to the API defaults
+type AllParams struct {
+	From     *uint64
+	To       *uint64
+	FromTime *uint64
+	ToTime   *uint64
+	Bin      *uint64
+	Order    string // asc or desc
+	Limit    *uint64
+	Cursor   string
+}
+
+func (c *Client) All(ctx context.Context, p AllParams) (*BlockStatPage, error) {
+	q := url.Values{}
+	setUint(q, "from", p.From)
+	setUint(q, "to", p.To)
+	setUint(q, "fromTime", p.FromTime)
+	setUint(q, "toTime", p.ToTime)
+	setUint(q, "bin", p.Bin)
+	setUint(q, "limit", p.Limit)
+	if p.Order != "" {
+		q.Set("order", p.Order)
+	}
+	if p.Cursor != "" {
+		q.Set("cursor", p.Cursor)
+	}
+
+	var page BlockStatPage
+	if err := c.do(ctx, http.MethodGet, "/all", q, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Aggregated stats of the latest `count` blocks, 0 uses the configured amount
+func (c *Client) Latest(ctx context.Context, count uint64) (*IntervalStat, error) {
+	q := url.Values{}
+	if count != 0 {
+		q.Set("count", strconv.FormatUint(count, 10))
+	}
+
+	var stats IntervalStat
+	if err := c.do(ctx, http.MethodGet, "/latest", q, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (c *Client) SLO(ctx context.Context) ([]SLOReport, error) {
+	var reports []SLOReport
+	err := c.do(ctx, http.MethodGet, "/slo", nil, nil, &reports)
+	return reports, err
+}
+
+// Parameters of `/messages`, zero values are left to the API defaults
+type MessagesParams struct {
+	Status string // pending, relayed or expired
+	MinAge uint64 // in seconds, only pending messages sent at least this long ago
+	Limit  uint64
+}
+
+func (c *Client) Messages(ctx context.Context, p MessagesParams) ([]MessageRecord, error) {
+	q := url.Values{}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.MinAge != 0 {
+		q.Set("minAge", strconv.FormatUint(p.MinAge, 10))
+	}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.FormatUint(p.Limit, 10))
+	}
+
+	var records []MessageRecord
+	err := c.do(ctx, http.MethodGet, "/messages", q, nil, &records)
+	return records, err
+}
+
+// Relay latency percentiles per bucket of `bucket` seconds since the given time, zero values use the API defaults
+func (c *Client) Latency(ctx context.Context, bucket uint64, since time.Time) (*LatencyReport, error) {
+	q := url.Values{}
+	if bucket != 0 {
+		q.Set("bucket", strconv.FormatUint(bucket, 10))
+	}
+	setTime(q, "since", since)
+
+	var report LatencyReport
+	if err := c.do(ctx, http.MethodGet, "/latency", q, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Message counts by `target` or `sender`, a limit of 0 uses the API default
+func (c *Client) Breakdown(ctx context.Context, by string, limit uint64) (*BreakdownReport, error) {
+	q := url.Values{}
+	q.Set("by", by)
+	if limit != 0 {
+		q.Set("limit", strconv.FormatUint(limit, 10))
+	}
+
+	var report BreakdownReport
+	if err := c.do(ctx, http.MethodGet, "/breakdown", q, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Relayers ordered by executed messages, a limit of 0 uses the API default
+func (c *Client) Relayers(ctx context.Context, limit uint64) (*RelayerReport, error) {
+	q := url.Values{}
+	if limit != 0 {
+		q.Set("limit", strconv.FormatUint(limit, 10))
+	}
+
+	var report RelayerReport
+	if err := c.do(ctx, http.MethodGet, "/relayers", q, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Relay costs bucketed by time, a bucket of 0 uses the API default
+func (c *Client) Costs(ctx context.Context, bucket uint64, since time.Time) (*CostReport, error) {
+	q := url.Values{}
+	if bucket != 0 {
+		q.Set("bucket", strconv.FormatUint(bucket, 10))
+	}
+	setTime(q, "since", since)
+
+	var report CostReport
+	if err := c.do(ctx, http.MethodGet, "/costs", q, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Recent relay transactions newest first, optionally of a single relayer. A limit of 0 uses the API default
+func (c *Client) RelayCosts(ctx context.Context, relayer *common.Address, limit uint64) ([]RelayCost, error) {
+	q := url.Values{}
+	if relayer != nil {
+		q.Set("relayer", relayer.Hex())
+	}
+	if limit != 0 {
+		q.Set("limit", strconv.FormatUint(limit, 10))
+	}
+
+	var costs []RelayCost
+	if err := c.do(ctx, http.MethodGet, "/costs/transactions", q, nil, &costs); err != nil {
+		return nil, err
+	}
+	return costs, nil
+}
+
+// Recent reverted executions newest first, optionally with a single reason. A limit of 0 uses the API default
+func (c *Client) Failures(ctx context.Context, reason string, limit uint64) (*FailureReport, error) {
+	q := url.Values{}
+	if reason != "" {
+		q.Set("reason", reason)
+	}
+	if limit != 0 {
+		q.Set("limit", strconv.FormatUint(limit, 10))
+	}
+
+	var report FailureReport
+	if err := c.do(ctx, http.MethodGet, "/failures", q, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Messages found relayed on-chain while the monitor had them pending or expired, newest first.
+// A limit of 0 uses the API default
+func (c *Client) Reconciliation(ctx context.Context, limit uint64) (*ReconciliationReport, error) {
+	q := url.Values{}
+	if limit != 0 {
+		q.Set("limit", strconv.FormatUint(limit, 10))
+	}
+
+	var report ReconciliationReport
+	if err := c.do(ctx, http.MethodGet, "/reconciliation", q, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Decoded amounts of the pending messages by token
+func (c *Client) Inflight(ctx context.Context) (*InflightReport, error) {
+	var report InflightReport
+	if err := c.do(ctx, http.MethodGet, "/inflight", nil, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Nonce gaps of the sender messenger and relay replays, newest first. A limit of 0 uses the API default
+func (c *Client) Nonces(ctx context.Context, limit uint64) (*NonceReport, error) {
+	q := url.Values{}
+	if limit != 0 {
+		q.Set("limit", strconv.FormatUint(limit, 10))
+	}
+
+	var report NonceReport
+	if err := c.do(ctx, http.MethodGet, "/nonces", q, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Executing messages of the CrossL2Inbox by origin contract, with the recent invalid ones newest
+// first. A limit of 0 uses the API default
+func (c *Client) Origins(ctx context.Context, limit uint64) (*OriginReport, error) {
+	q := url.Values{}
+	if limit != 0 {
+		q.Set("limit", strconv.FormatUint(limit, 10))
+	}
+
+	var report OriginReport
+	if err := c.do(ctx, http.MethodGet, "/origins", q, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Chain heads and safety levels according to op-supervisor, with the disagreements newest first.
+// A limit of 0 uses the API default
+func (c *Client) Supervisor(ctx context.Context, limit uint64) (*SupervisorReport, error) {
+	q := url.Values{}
+	if limit != 0 {
+		q.Set("limit", strconv.FormatUint(limit, 10))
+	}
+
+	var report SupervisorReport
+	if err := c.do(ctx, http.MethodGet, "/supervisor", q, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Monitored pairs, the configured one and the ones discovered from the dependency set
+func (c *Client) Pairs(ctx context.Context) ([]PairInfo, error) {
+	var pairs []PairInfo
+	if err := c.do(ctx, http.MethodGet, "/pairs", nil, nil, &pairs); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// Returned when a GraphQL query has errors
+type GraphQLError struct {
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "graphql: " + strings.Join(e.Messages, "; ")
+}
+
+// Runs a GraphQL query, decoding its `data` into out
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	body := map[string]interface{}{"query": query, "variables": variables}
+
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/graphql", nil, body, &res); err != nil {
+		return err
+	}
+
+	if len(res.Errors) > 0 {
+		gqlErr := &GraphQLError{}
+		for _, e := range res.Errors {
+			gqlErr.Messages = append(gqlErr.Messages, e.Message)
+		}
+		return gqlErr
+	}
+
+	if out == nil || len(res.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(res.Data, out)
+}
+
+func (c *Client) Alerts(ctx context.Context) ([]ActiveAlert, error) {
+	var alerts []ActiveAlert
+	err := c.do(ctx, http.MethodGet, "/alerts", nil, nil, &alerts)
+	return alerts, err
+}
+
+// Parameters of `/alerts/history`, zero values match everything
+type AlertHistoryParams struct {
+	From  time.Time
+	To    time.Time
+	Rule  string
+	Pair  string
+	Limit int
+}
+
+func (c *Client) AlertHistory(ctx context.Context, p AlertHistoryParams) ([]AlertEvent, error) {
+	q := url.Values{}
+	setTime(q, "from", p.From)
+	setTime(q, "to", p.To)
+	if p.Rule != "" {
+		q.Set("rule", p.Rule)
+	}
+	if p.Pair != "" {
+		q.Set("pair", p.Pair)
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+
+	var events []AlertEvent
+	err := c.do(ctx, http.MethodGet, "/alerts/history", q, nil, &events)
+	return events, err
+}
+
+// Alert deliveries, an empty status returns all of them
+func (c *Client) AlertDeliveries(ctx context.Context, status string) ([]Delivery, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+
+	var deliveries []Delivery
+	err := c.do(ctx, http.MethodGet, "/alerts/deliveries", q, nil, &deliveries)
+	return deliveries, err
+}
+
+func (c *Client) AcknowledgeAlert(ctx context.Context, rule, pair string) (*ActiveAlert, error) {
+	body := map[string]string{"rule": rule, "pair": pair}
+
+	var alert ActiveAlert
+	if err := c.do(ctx, http.MethodPost, "/alerts/ack", nil, body, &alert); err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (c *Client) Silences(ctx context.Context, activeOnly bool) ([]Silence, error) {
+	q := url.Values{}
+	if activeOnly {
+		q.Set("active", "true")
+	}
+
+	var silences []Silence
+	err := c.do(ctx, http.MethodGet, "/silences", q, nil, &silences)
+	return silences, err
+}
+
+func (c *Client) CreateSilence(ctx context.Context, req SilenceRequest) (*Silence, error) {
+	var silence Silence
+	if err := c.do(ctx, http.MethodPost, "/silences", nil, req, &silence); err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+func (c *Client) ExpireSilence(ctx context.Context, id uint64) (*Silence, error) {
+	var silence Silence
+	if err := c.do(ctx, http.MethodDelete, "/silences/"+strconv.FormatUint(id, 10), nil, nil, &silence); err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// Filters of `/stream`, empty lists match everything
+type StreamParams struct {
+	Types   []string
+	Pairs   []string
+	Targets []string
+}
+
+// Reads the Server-Sent Events stream, calling handle for every event until the context is done,
+// the connection is closed or handle returns an error
+func (c *Client) Stream(ctx context.Context, p StreamParams, handle func(StreamEvent) error) error {
+	q := url.Values{}
+	if len(p.Types) > 0 {
+		q.Set("type", strings.Join(p.Types, ","))
+	}
+	if len(p.Pairs) > 0 {
+		q.Set("pair", strings.Join(p.Pairs, ","))
+	}
+	if len(p.Targets) > 0 {
+		q.Set("target", strings.Join(p.Targets, ","))
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/stream", q, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err := decodeResponse(res, nil); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		// only the data lines are needed, the event name is repeated in the payload
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var ev StreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return err
+		}
+
+		if err := handle(ev); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,433 @@
+package client
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Identifier of a message sent on the sender chain
+type Identifier struct {
+	Origin      common.Address `json:"origin"`
+	BlockNumber uint64         `json:"blockNumber"`
+	LogIndex    uint64         `json:"logIndex"`
+	Timestamp   uint64         `json:"timestamp"`
+	ChainId     uint64         `json:"chainId"`
+}
+
+// A single block or bin of `/all`
+type BlockStatItem struct {
+	Block            uint64  `json:"block"`
+	Timestamp        uint64  `json:"timestamp"`
+	MessageCount     uint64  `json:"messageCount"`
+	AvgLatency       float64 `json:"avgLatency"`
+	MissingMessages  uint64  `json:"missingMessages"`
+	MissingReception uint64  `json:"missingReception"`
+	MissingRelay     uint64  `json:"missingRelay"`
+}
+
+type BlockStatPage struct {
+	Items      []BlockStatItem `json:"items"`
+	NextCursor *string         `json:"nextCursor"`
+}
+
+// Aggregated stats of `/latest`
+type IntervalStat struct {
+	MessageCount     uint64   `json:"messageCount"`
+	TotalLatency     *big.Int `json:"totalLatency"`
+	AvgLatency       float64  `json:"avgLatency"`
+	SentMessages     uint64   `json:"sentMessages"`
+	ReceivedMessages uint64   `json:"receivedMessages"`
+	MissingRelay     uint64   `json:"missingRelay"`
+	MissingReception uint64   `json:"missingReception"`
+}
+
+type SLOWindowReport struct {
+	Total                uint64  `json:"total"`
+	Good                 uint64  `json:"good"`
+	Compliance           float64 `json:"compliance"`
+	BurnRate             float64 `json:"burnRate"`
+	ErrorBudgetRemaining float64 `json:"errorBudgetRemaining"`
+}
+
+type SLOReport struct {
+	Pair                 string                     `json:"pair"`
+	Name                 string                     `json:"name"`
+	Objective            float64                    `json:"objective"`
+	LatencyTarget        uint64                     `json:"latencyTarget"`
+	ErrorBudgetRemaining float64                    `json:"errorBudgetRemaining"`
+	Windows              map[string]SLOWindowReport `json:"windows"`
+}
+
+type ActiveAlert struct {
+	Rule           string     `json:"rule"`
+	Type           string     `json:"type"`
+	Severity       string     `json:"severity"`
+	Pair           string     `json:"pair"`
+	Value          string     `json:"value"`
+	Threshold      string     `json:"threshold"`
+	Since          time.Time  `json:"since"`
+	Acknowledged   bool       `json:"acknowledged"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	Silenced       bool       `json:"silenced"`
+}
+
+type AlertEvent struct {
+	Time      time.Time    `json:"time"`
+	Event     string       `json:"event"`
+	Rule      string       `json:"rule"`
+	Type      string       `json:"type"`
+	Severity  string       `json:"severity"`
+	Pair      string       `json:"pair"`
+	Value     string       `json:"value"`
+	Threshold string       `json:"threshold"`
+	Stats     IntervalStat `json:"stats"`
+}
+
+type Delivery struct {
+	ID          uint64     `json:"id"`
+	Channel     string     `json:"channel"`
+	AlertType   string     `json:"alertType"`
+	Payload     string     `json:"payload"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	NextAttempt time.Time  `json:"nextAttempt"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+type Silence struct {
+	ID        uint64    `json:"id"`
+	Rule      string    `json:"rule,omitempty"`
+	Severity  string    `json:"severity,omitempty"`
+	Pair      string    `json:"pair,omitempty"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Body of a silence creation, `Duration` (e.g. `2h`) can be used instead of `EndsAt`
+type SilenceRequest struct {
+	Rule     string     `json:"rule,omitempty"`
+	Severity string     `json:"severity,omitempty"`
+	Pair     string     `json:"pair,omitempty"`
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	Duration string     `json:"duration,omitempty"`
+	Comment  string     `json:"comment,omitempty"`
+}
+
+type ChainHealth struct {
+	Pair                  string    `json:"pair"`
+	Side                  string    `json:"side"`
+	ChainId               string    `json:"chainId"`
+	RPCReachable          bool      `json:"rpcReachable"`
+	RPCError              string    `json:"rpcError,omitempty"`
+	Head                  uint64    `json:"head"`
+	Cursor                uint64    `json:"cursor"`
+	HeadLag               uint64    `json:"headLag"`
+	LastSuccessfulFetch   time.Time `json:"lastSuccessfulFetch"`
+	SecondsSinceLastFetch float64   `json:"secondsSinceLastFetch"`
+	LastFetchError        string    `json:"lastFetchError,omitempty"`
+}
+
+type AlertEngineHealth struct {
+	LastEvaluation             time.Time `json:"lastEvaluation"`
+	SecondsSinceLastEvaluation float64   `json:"secondsSinceLastEvaluation"`
+	PendingDeliveries          int       `json:"pendingDeliveries"`
+}
+
+type Readiness struct {
+	Ready       bool              `json:"ready"`
+	Problems    []string          `json:"problems"`
+	Chains      []ChainHealth     `json:"chains"`
+	AlertEngine AlertEngineHealth `json:"alertEngine"`
+}
+
+// Event of `/stream`, `Data` depends on the event type
+type StreamEvent struct {
+	Type   string          `json:"type"`
+	Pair   string          `json:"pair"`
+	Time   time.Time       `json:"time"`
+	Target string          `json:"target,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type MessageRecord struct {
+	Id            Identifier      `json:"id"`
+	Target        common.Address  `json:"target"`
+	Sender        common.Address  `json:"sender"`
+	Nonce         *big.Int        `json:"nonce,omitempty"`
+	MessageHash   *common.Hash    `json:"messageHash,omitempty"`
+	Status        string          `json:"status"`
+	SentBlock     uint64          `json:"sentBlock"`
+	SentAt        time.Time       `json:"sentAt"`
+	ExecutedBlock uint64          `json:"executedBlock,omitempty"`
+	ExecutedAt    *time.Time      `json:"executedAt,omitempty"`
+	Latency       *uint64         `json:"latency,omitempty"` // in seconds
+	Relayer       *common.Address `json:"relayer,omitempty"`
+	ExecutionTx   *common.Hash    `json:"executionTx,omitempty"`
+	Failures      uint64          `json:"failures,omitempty"`
+	LastFailure   string          `json:"lastFailure,omitempty"`
+	Payload       *Payload        `json:"payload,omitempty"`
+}
+
+type Payload struct {
+	Decoder   string            `json:"decoder"`
+	Method    string            `json:"method"`
+	Token     *common.Address   `json:"token,omitempty"` // zero address for ETH
+	From      *common.Address   `json:"from,omitempty"`
+	Recipient *common.Address   `json:"recipient,omitempty"`
+	Amount    *big.Int          `json:"amount,omitempty"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+type LatencyBucket struct {
+	Time  time.Time `json:"time"`
+	Count int       `json:"count"`
+	P50   uint64    `json:"p50"`
+	P90   uint64    `json:"p90"`
+	P99   uint64    `json:"p99"`
+	Max   uint64    `json:"max"`
+}
+
+type LatencyReport struct {
+	Pair    string          `json:"pair"`
+	Bucket  uint64          `json:"bucket"` // in seconds
+	Buckets []LatencyBucket `json:"buckets"`
+}
+
+type BreakdownStat struct {
+	Key          string    `json:"key"` // address, or "other"
+	Sent         uint64    `json:"sent"`
+	Relayed      uint64    `json:"relayed"`
+	Expired      uint64    `json:"expired"`
+	Reconciled   uint64    `json:"reconciled"`
+	Pending      uint64    `json:"pending"`
+	TotalLatency uint64    `json:"totalLatency"`
+	AvgLatency   float64   `json:"avgLatency"`
+	MaxLatency   uint64    `json:"maxLatency"`
+	LastSeen     time.Time `json:"lastSeen"`
+	Amount       *big.Int  `json:"amount,omitempty"`
+}
+
+type BreakdownReport struct {
+	Pair  string          `json:"pair"`
+	By    string          `json:"by"`
+	Items []BreakdownStat `json:"items"`
+	Other BreakdownStat   `json:"other"`
+}
+
+type RelayerStat struct {
+	Address      string    `json:"address"` // or "other"
+	Messages     uint64    `json:"messages"`
+	Transactions uint64    `json:"transactions"`
+	Failed       uint64    `json:"failed"`
+	FailureRate  float64   `json:"failureRate"`
+	GasUsed      uint64    `json:"gasUsed"`
+	L1Fee        *big.Int  `json:"l1Fee"` // in wei
+	Cost         *big.Int  `json:"cost"`  // in wei
+	TotalLatency uint64    `json:"totalLatency"`
+	Latencies    uint64    `json:"latencies"`
+	AvgLatency   float64   `json:"avgLatency"`
+	MaxLatency   uint64    `json:"maxLatency"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
+
+type RelayerReport struct {
+	Pair  string        `json:"pair"`
+	Items []RelayerStat `json:"items"`
+	Other RelayerStat   `json:"other"`
+}
+
+type RelayCost struct {
+	TxHash            common.Hash    `json:"txHash"`
+	Relayer           common.Address `json:"relayer"`
+	Block             uint64         `json:"block"`
+	At                time.Time      `json:"at"`
+	Messages          uint64         `json:"messages"`
+	Failed            bool           `json:"failed"`
+	GasUsed           uint64         `json:"gasUsed"`
+	EffectiveGasPrice *big.Int       `json:"effectiveGasPrice"`
+	L1Fee             *big.Int       `json:"l1Fee"`
+	Cost              *big.Int       `json:"cost"`
+}
+
+// Amounts are in wei
+type CostStat struct {
+	Transactions      uint64   `json:"transactions"`
+	Failed            uint64   `json:"failed"`
+	Messages          uint64   `json:"messages"`
+	GasUsed           uint64   `json:"gasUsed"`
+	L1Fee             *big.Int `json:"l1Fee"`
+	Cost              *big.Int `json:"cost"`
+	AvgCostPerMessage float64  `json:"avgCostPerMessage"`
+}
+
+type CostBucket struct {
+	Time time.Time `json:"time"`
+	CostStat
+}
+
+type CostReport struct {
+	Pair    string       `json:"pair"`
+	Bucket  uint64       `json:"bucket"`
+	Total   CostStat     `json:"total"`
+	Buckets []CostBucket `json:"buckets"`
+}
+
+type ExecutionFailure struct {
+	TxHash    common.Hash    `json:"txHash"`
+	Block     uint64         `json:"block"`
+	At        time.Time      `json:"at"`
+	Relayer   common.Address `json:"relayer"`
+	To        common.Address `json:"to"`
+	Method    string         `json:"method"`
+	MessageId *Identifier    `json:"messageId,omitempty"`
+	Reason    string         `json:"reason"`
+	GasUsed   uint64         `json:"gasUsed"`
+}
+
+type FailureReport struct {
+	Pair     string             `json:"pair"`
+	Total    uint64             `json:"total"`
+	ByReason map[string]uint64  `json:"byReason"`
+	Items    []ExecutionFailure `json:"items"`
+}
+
+type Discrepancy struct {
+	Id             Identifier     `json:"id"`
+	MessageHash    common.Hash    `json:"messageHash"`
+	Target         common.Address `json:"target"`
+	PreviousStatus string         `json:"previousStatus"`
+	DetectedAt     time.Time      `json:"detectedAt"`
+}
+
+type InflightToken struct {
+	Token           common.Address `json:"token"` // zero address for ETH
+	Messages        uint64         `json:"messages"`
+	Amount          *big.Int       `json:"amount"`
+	ExpiredMessages uint64         `json:"expiredMessages"`
+	Expired         *big.Int       `json:"expired"` // part of the amount in expired messages
+	OldestSentAt    time.Time      `json:"oldestSentAt"`
+	Threshold       *float64       `json:"threshold,omitempty"`
+}
+
+type InflightReport struct {
+	Pair      string          `json:"pair"`
+	Tokens    []InflightToken `json:"tokens"`
+	Undecoded uint64          `json:"undecoded"`
+}
+
+type NonceGap struct {
+	From       uint64    `json:"from"`
+	To         uint64    `json:"to"`
+	Block      uint64    `json:"block"`
+	DetectedAt time.Time `json:"detectedAt"`
+	Filled     uint64    `json:"filled"`
+}
+
+type Replay struct {
+	MessageHash common.Hash `json:"messageHash"`
+	Nonce       *big.Int    `json:"nonce"`
+	FirstTx     common.Hash `json:"firstTx"`
+	FirstBlock  uint64      `json:"firstBlock"`
+	TxHash      common.Hash `json:"txHash"`
+	Block       uint64      `json:"block"`
+	DetectedAt  time.Time   `json:"detectedAt"`
+}
+
+type NonceReport struct {
+	Pair      string     `json:"pair"`
+	Messenger string     `json:"messenger"`
+	First     *uint64    `json:"first"`
+	Last      *uint64    `json:"last"`
+	Sent      uint64     `json:"sent"`
+	Missing   uint64     `json:"missing"`
+	Gaps      []NonceGap `json:"gaps"`
+	Replayed  uint64     `json:"replayed"`
+	Replays   []Replay   `json:"replays"`
+}
+
+type OriginStat struct {
+	Origin       string    `json:"origin"` // "other" for the origins not listed
+	Executions   uint64    `json:"executions"`
+	Resolved     uint64    `json:"resolved"`
+	Invalid      uint64    `json:"invalid"`
+	Unresolved   uint64    `json:"unresolved"`
+	TotalLatency uint64    `json:"totalLatency"`
+	AvgLatency   float64   `json:"avgLatency"`
+	MaxLatency   uint64    `json:"maxLatency"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
+
+type OriginExecution struct {
+	Id         Identifier  `json:"id"`
+	MsgHash    common.Hash `json:"msgHash"`
+	TxHash     common.Hash `json:"txHash"`
+	Block      uint64      `json:"block"`
+	ExecutedAt time.Time   `json:"executedAt"`
+	Status     string      `json:"status"`
+	Reason     string      `json:"reason,omitempty"`
+	Latency    *uint64     `json:"latency,omitempty"`
+}
+
+type OriginReport struct {
+	Pair    string            `json:"pair"`
+	Items   []OriginStat      `json:"items"`
+	Other   OriginStat        `json:"other"`
+	Invalid []OriginExecution `json:"invalid"`
+}
+
+type SupervisorChain struct {
+	ChainId      uint64 `json:"chainId"`
+	LocalUnsafe  uint64 `json:"localUnsafe"`
+	CrossUnsafe  uint64 `json:"crossUnsafe"`
+	LocalSafe    uint64 `json:"localSafe"`
+	CrossSafe    uint64 `json:"crossSafe"`
+	Finalized    uint64 `json:"finalized"`
+	CrossSafeLag uint64 `json:"crossSafeLag"`
+}
+
+type Disagreement struct {
+	Id         Identifier  `json:"id"`
+	MsgHash    common.Hash `json:"msgHash"`
+	TxHash     common.Hash `json:"txHash"`
+	Block      uint64      `json:"block"`
+	Safety     string      `json:"safety"`
+	View       string      `json:"view"`
+	DetectedAt time.Time   `json:"detectedAt"`
+}
+
+type SupervisorReport struct {
+	Pair          string            `json:"pair"`
+	LastRun       *time.Time        `json:"lastRun"`
+	Chains        []SupervisorChain `json:"chains"`
+	Queued        int               `json:"queued"`
+	Dropped       uint64            `json:"dropped"`
+	Checked       uint64            `json:"checked"`
+	Failed        uint64            `json:"failed"`
+	Levels        map[string]uint64 `json:"levels"`
+	Total         uint64            `json:"total"`
+	Disagreements []Disagreement    `json:"disagreements"`
+}
+
+type ReconciliationReport struct {
+	Pair          string        `json:"pair"`
+	LastRun       *time.Time    `json:"lastRun"`
+	Checked       uint64        `json:"checked"`
+	Total         uint64        `json:"total"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+type PairInfo struct {
+	Pair       string    `json:"pair"`
+	Sender     string    `json:"sender"`   // chain id
+	Receiver   string    `json:"receiver"` // chain id
+	Discovered bool      `json:"discovered"`
+	AddedAt    time.Time `json:"addedAt"`
+}
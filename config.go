@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 type Config struct {
@@ -19,7 +21,62 @@ type Config struct {
 	TelegramToken            string  `json:"telegramToken"`
 	TelegramChatId           string  `json:"telegramChatId"`
 	DiscordWebhookURL        string  `json:"discordWebhookURL"`
+	SlackWebhookURL          string  `json:"slackWebhookURL"`
 	CustomWebhookURL         string  `json:"customWebhookURL"`
+	TelegramTemplate         string  `json:"telegramTemplate"`
+	TelegramParseMode        string  `json:"telegramParseMode"`
+	DiscordTemplate          string  `json:"discordTemplate"`
+	SlackTemplate            string  `json:"slackTemplate"`
+	CustomWebhookTemplate    string  `json:"customWebhookTemplate"`
+	AlertOutboxFile          string  `json:"alertOutboxFile"`
+	AlertMaxAttempts         int     `json:"alertMaxAttempts"`
+	SilencesFile             string  `json:"silencesFile"`
+	AlertHistoryFile         string  `json:"alertHistoryFile"`
+	AlertHistorySize         int     `json:"alertHistorySize"`
+	AlertNoMessagesMinutes   uint64  `json:"alertNoMessagesMinutes"`
+	AlertFetchStallMinutes   uint64  `json:"alertFetchStallMinutes"`
+	AlertHeadStallMinutes    uint64  `json:"alertHeadStallMinutes"`
+	AlertCostSpikeRatio      float64 `json:"alertCostSpikeRatio"`
+	AlertFailedExecutionsMin uint64  `json:"alertFailedExecutionsMin"`
+	AlertMissedRelaysMin     uint64  `json:"alertMissedRelaysMin"`
+	AlertNonceGaps           bool    `json:"alertNonceGaps"`
+	AlertRelayReplays        bool    `json:"alertRelayReplays"`
+	ReconcileTime            int     `json:"reconcileTime"`
+	TrackInboxOrigins        bool    `json:"trackInboxOrigins"`
+	SupervisorURL            string  `json:"supervisorURL"`
+	SupervisorCheckTime      int     `json:"supervisorCheckTime"`
+	AlertSupervisorMismatch  bool    `json:"alertSupervisorMismatch"`
+	AlertCrossSafeLagBlocks  uint64  `json:"alertCrossSafeLagBlocks"`
+	LivenessCheckTime        int     `json:"livenessCheckTime"`
+	HeartbeatURL             string  `json:"heartbeatURL"`
+	ReadyMaxHeadLag          uint64  `json:"readyMaxHeadLag"`
+	ReadyMaxFetchAge         int     `json:"readyMaxFetchAge"`
+	AnomalyDetection         bool    `json:"anomalyDetection"`
+	AnomalySensitivity       float64 `json:"anomalySensitivity"`
+	AnomalyAlpha             float64 `json:"anomalyAlpha"`
+	AnomalyWarmup            int     `json:"anomalyWarmup"`
+	MessageHistorySize       int     `json:"messageHistorySize"`
+	SLOs                     []SLO   `json:"slos"`
+	SLOCheckTime             int     `json:"sloCheckTime"`
+
+	APIHost        string   `json:"apiHost"`
+	APIDebug       bool     `json:"apiDebug"`
+	APIKeys        []APIKey `json:"apiKeys"`
+	APICorsOrigins []string `json:"apiCorsOrigins"`
+	APIRateLimit   float64  `json:"apiRateLimit"` // requests per second per client
+	APIRateBurst   int      `json:"apiRateBurst"`
+	APITLSCertFile string   `json:"apiTlsCertFile"`
+	APITLSKeyFile  string   `json:"apiTlsKeyFile"`
+
+	BreakdownSize      int                        `json:"breakdownSize"`
+	AlertRules         []AlertRule                `json:"alertRules"`
+	PayloadDecoders    []PayloadDecoderConfig     `json:"payloadDecoders"`
+	AlertInflightValue map[common.Address]float64 `json:"alertInflightValue"` // threshold by token, in its smallest unit
+	DependencySet      *DependencySetConfig       `json:"dependencySet"`
+	RPCs               map[uint64]string          `json:"rpcs"` // by chain id, for the chains of the dependency set
+
+	templates alertTemplates
+	payloads  *PayloadRegistry
 }
 
 func parseConfig(data []byte) (*Config, error) {
@@ -38,7 +95,57 @@ func parseConfig(data []byte) (*Config, error) {
 		TelegramToken:            "",
 		TelegramChatId:           "",
 		DiscordWebhookURL:        "",
+		SlackWebhookURL:          "",
 		CustomWebhookURL:         "",
+		TelegramTemplate:         "",
+		TelegramParseMode:        "HTML",
+		DiscordTemplate:          "",
+		SlackTemplate:            "",
+		CustomWebhookTemplate:    "",
+		AlertOutboxFile:          "alert_outbox.json",
+		AlertMaxAttempts:         10,
+		SilencesFile:             "silences.json",
+		AlertHistoryFile:         "alert_history.jsonl",
+		AlertHistorySize:         10000,
+		AlertNoMessagesMinutes:   0,
+		AlertFetchStallMinutes:   0,
+		AlertHeadStallMinutes:    0,
+		AlertCostSpikeRatio:      0,
+		AlertFailedExecutionsMin: 0,
+		AlertMissedRelaysMin:     0,
+		AlertNonceGaps:           false,
+		AlertRelayReplays:        false,
+		ReconcileTime:            60,
+		TrackInboxOrigins:        false,
+		SupervisorURL:            "",
+		SupervisorCheckTime:      30,
+		AlertSupervisorMismatch:  false,
+		AlertCrossSafeLagBlocks:  0,
+		LivenessCheckTime:        30,
+		HeartbeatURL:             "",
+		ReadyMaxHeadLag:          50,
+		ReadyMaxFetchAge:         60,
+		AnomalyDetection:         false,
+		AnomalySensitivity:       3,
+		AnomalyAlpha:             0.1,
+		AnomalyWarmup:            30,
+		MessageHistorySize:       10000,
+		SLOs:                     nil,
+		SLOCheckTime:             60,
+		APIHost:                  "",
+		APIDebug:                 false,
+		APIKeys:                  nil,
+		APICorsOrigins:           nil,
+		APIRateLimit:             0,
+		APIRateBurst:             20,
+		APITLSCertFile:           "",
+		APITLSKeyFile:            "",
+		BreakdownSize:            100,
+		AlertRules:               nil,
+		PayloadDecoders:          nil,
+		AlertInflightValue:       nil,
+		DependencySet:            nil,
+		RPCs:                     nil,
 	}
 
 	if err := json.Unmarshal(data, config); err != nil {
@@ -46,13 +153,105 @@ func parseConfig(data []byte) (*Config, error) {
 	}
 
 	// Validate required fields
-	if config.SenderChain == "" || config.ReceiverChain == "" {
-		return nil, fmt.Errorf("senderChain and receiverChain are required")
+	if err := validateDependencySet(config); err != nil {
+		return nil, err
 	}
 
 	if config.TelegramToken != "" && config.TelegramChatId == "" {
 		return nil, fmt.Errorf("telegramChatId must be provided for Telegram alerts")
 	}
 
+	if config.AlertMaxAttempts < 1 {
+		return nil, fmt.Errorf("alertMaxAttempts must be at least 1")
+	}
+
+	if config.AlertHistorySize < 1 {
+		return nil, fmt.Errorf("alertHistorySize must be at least 1")
+	}
+
+	if config.LivenessCheckTime < 1 {
+		return nil, fmt.Errorf("livenessCheckTime must be at least 1")
+	}
+
+	if config.AnomalySensitivity <= 0 {
+		return nil, fmt.Errorf("anomalySensitivity must be positive")
+	}
+
+	if config.AnomalyAlpha <= 0 || config.AnomalyAlpha > 1 {
+		return nil, fmt.Errorf("anomalyAlpha must be in (0, 1]")
+	}
+
+	if config.AnomalyWarmup < 1 {
+		return nil, fmt.Errorf("anomalyWarmup must be at least 1")
+	}
+
+	if config.AlertCostSpikeRatio != 0 && config.AlertCostSpikeRatio <= 1 {
+		return nil, fmt.Errorf("alertCostSpikeRatio must be greater than 1, or 0 to disable it")
+	}
+
+	if config.ReconcileTime < 0 {
+		return nil, fmt.Errorf("reconcileTime must not be negative")
+	}
+
+	if config.SupervisorURL != "" && config.SupervisorCheckTime < 1 {
+		return nil, fmt.Errorf("supervisorCheckTime must be at least 1")
+	}
+
+	if config.MessageHistorySize < 1 {
+		return nil, fmt.Errorf("messageHistorySize must be at least 1")
+	}
+
+	if config.SLOCheckTime < 1 {
+		return nil, fmt.Errorf("sloCheckTime must be at least 1")
+	}
+
+	if err := validateSLOs(config.SLOs); err != nil {
+		return nil, err
+	}
+
+	if config.BreakdownSize < 1 {
+		return nil, fmt.Errorf("breakdownSize must be at least 1")
+	}
+
+	if err := validateAlertRules(config.AlertRules); err != nil {
+		return nil, err
+	}
+
+	for token, threshold := range config.AlertInflightValue {
+		if threshold <= 0 {
+			return nil, fmt.Errorf("alertInflightValue: threshold of %s must be positive", token.Hex())
+		}
+	}
+
+	payloads, err := NewPayloadRegistry(config.PayloadDecoders)
+	if err != nil {
+		return nil, err
+	}
+	config.payloads = payloads
+
+	if err := validateAPIKeys(config.APIKeys); err != nil {
+		return nil, err
+	}
+
+	if config.APIRateLimit < 0 || config.APIRateBurst < 1 {
+		return nil, fmt.Errorf("apiRateLimit must not be negative and apiRateBurst must be at least 1")
+	}
+
+	if (config.APITLSCertFile == "") != (config.APITLSKeyFile == "") {
+		return nil, fmt.Errorf("apiTlsCertFile and apiTlsKeyFile must be set together")
+	}
+
+	switch config.TelegramParseMode {
+	case "", "HTML", "MarkdownV2", "Markdown":
+	default:
+		return nil, fmt.Errorf("telegramParseMode must be one of \"HTML\", \"MarkdownV2\", \"Markdown\" or \"\"")
+	}
+
+	templates, err := parseAlertTemplates(config)
+	if err != nil {
+		return nil, err
+	}
+	config.templates = templates
+
 	return config, nil
 }
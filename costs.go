@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Returns the L2 execution fee plus the L1 data fee, both in wei. Nodes that don't report
+// the effective gas price or the L1 fee count them as 0
+func (r *Receipt) Fees() (l1Fee, cost *big.Int) {
+	l1Fee = new(big.Int)
+	if r.L1Fee != nil {
+		l1Fee.Set(r.L1Fee.ToInt())
+	}
+
+	cost = new(big.Int)
+	if r.EffectiveGasPrice != nil {
+		cost.Mul(r.EffectiveGasPrice.ToInt(), new(big.Int).SetUint64(uint64(r.GasUsed)))
+	}
+	cost.Add(cost, l1Fee)
+
+	return
+}
+
+// Cost of a single relay transaction, amounts are in wei
+type RelayCost struct {
+	TxHash            common.Hash    `json:"txHash"`
+	Relayer           common.Address `json:"relayer"`
+	Block             uint64         `json:"block"`    // receiver block
+	At                time.Time      `json:"at"`       // when the receipt was fetched, shortly after the execution
+	Messages          uint64         `json:"messages"` // executed by the transaction
+	Failed            bool           `json:"failed"`   // reverted, executing no message
+	GasUsed           uint64         `json:"gasUsed"`
+	EffectiveGasPrice *big.Int       `json:"effectiveGasPrice"`
+	L1Fee             *big.Int       `json:"l1Fee"`
+	Cost              *big.Int       `json:"cost"` // gasUsed * effectiveGasPrice + l1Fee
+}
+
+// Relay costs summed over a window, amounts are in wei
+type CostStat struct {
+	Transactions      uint64   `json:"transactions"`
+	Failed            uint64   `json:"failed"` // reverted transactions, included in transactions
+	Messages          uint64   `json:"messages"`
+	GasUsed           uint64   `json:"gasUsed"`
+	L1Fee             *big.Int `json:"l1Fee"`
+	Cost              *big.Int `json:"cost"`
+	AvgCostPerMessage float64  `json:"avgCostPerMessage"`
+}
+
+func NewCostStat() CostStat {
+	return CostStat{L1Fee: new(big.Int), Cost: new(big.Int)}
+}
+
+// Amounts are replaced rather than mutated, since copies of the stat share them
+func (s *CostStat) add(c *RelayCost) {
+	s.Transactions++
+	if c.Failed {
+		s.Failed++
+	}
+	s.Messages += c.Messages
+	s.GasUsed += c.GasUsed
+	s.L1Fee = new(big.Int).Add(s.L1Fee, c.L1Fee)
+	s.Cost = new(big.Int).Add(s.Cost, c.Cost)
+}
+
+func (s *CostStat) finalize() {
+	if s.Messages > 0 {
+		s.AvgCostPerMessage = weiFloat(s.Cost) / float64(s.Messages)
+	}
+}
+
+func weiFloat(wei *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(wei).Float64()
+	return f
+}
+
+// Keeps the costs of the last `size` relay transactions, the totals since the monitor
+// started, and the baseline of the cost per message used to detect spikes
+type CostTracker struct {
+	recent   []*RelayCost // oldest first
+	byHash   map[common.Hash]*RelayCost
+	size     int
+	total    CostStat
+	window   CostStat // since the last spike check
+	baseline ewmaBaseline
+}
+
+func NewCostTracker(size int) *CostTracker {
+	return &CostTracker{
+		byHash: make(map[common.Hash]*RelayCost),
+		size:   size,
+		total:  NewCostStat(),
+		window: NewCostStat(),
+	}
+}
+
+// Called once per executed message, newTx is false for the next messages of the same transaction
+func (t *CostTracker) observe(receipt *Receipt, newTx bool) {
+	if !newTx {
+		if c, ok := t.byHash[receipt.TxHash]; ok {
+			c.Messages++
+		}
+		t.total.Messages++
+		t.window.Messages++
+		return
+	}
+
+	t.add(newRelayCost(receipt, 1))
+}
+
+// Reverted transactions execute no message, but their cost counts towards the cost per message
+func (t *CostTracker) observeFailed(receipt *Receipt) {
+	c := newRelayCost(receipt, 0)
+	c.Failed = true
+	t.add(c)
+}
+
+func newRelayCost(receipt *Receipt, messages uint64) *RelayCost {
+	l1Fee, cost := receipt.Fees()
+	c := &RelayCost{
+		TxHash:            receipt.TxHash,
+		Relayer:           receipt.From,
+		Block:             uint64(receipt.BlockNumber),
+		At:                time.Now().UTC(),
+		Messages:          messages,
+		GasUsed:           uint64(receipt.GasUsed),
+		EffectiveGasPrice: new(big.Int),
+		L1Fee:             l1Fee,
+		Cost:              cost,
+	}
+	if receipt.EffectiveGasPrice != nil {
+		c.EffectiveGasPrice.Set(receipt.EffectiveGasPrice.ToInt())
+	}
+
+	return c
+}
+
+func (t *CostTracker) add(c *RelayCost) {
+	t.total.add(c)
+	t.window.add(c)
+
+	t.byHash[c.TxHash] = c
+	t.recent = append(t.recent, c)
+	if len(t.recent) > t.size {
+		for _, old := range t.recent[:len(t.recent)-t.size] {
+			delete(t.byHash, old.TxHash)
+		}
+		t.recent = t.recent[len(t.recent)-t.size:]
+	}
+}
+
+// Scores the cost per message since the last check against the baseline, then learns from it.
+// Windows without relays are skipped, returning false
+func (t *CostTracker) check(config *Config, pair string) (AlertCheck, bool) {
+	window := t.window
+	t.window = NewCostStat()
+
+	if window.Messages == 0 {
+		return AlertCheck{}, false
+	}
+	window.finalize()
+
+	x := window.AvgCostPerMessage
+	threshold := config.AlertCostSpikeRatio * t.baseline.mean
+	warm := t.baseline.samples >= config.AnomalyWarmup
+
+	check := AlertCheck{
+		Rule:      "relayCostSpike",
+		Type:      "Relay Cost Spike",
+		Severity:  SeverityWarning,
+		Pair:      pair,
+		Firing:    warm && x > threshold,
+		Value:     fmt.Sprintf("%.0f wei per message", x),
+		Threshold: fmt.Sprintf("%.0f wei per message (%g × baseline)", threshold, config.AlertCostSpikeRatio),
+	}
+
+	t.baseline.update(x, config.AnomalyAlpha)
+
+	return check, true
+}
+
+// Costs of the relay transactions seen during a time bucket
+type CostBucket struct {
+	Time time.Time `json:"time"` // start of the bucket
+	CostStat
+}
+
+type CostReport struct {
+	Pair    string       `json:"pair"`
+	Bucket  uint64       `json:"bucket"`
+	Total   CostStat     `json:"total"` // since the monitor started
+	Buckets []CostBucket `json:"buckets"`
+}
+
+// Buckets the recent relay transactions by time, oldest first
+func (agg *Aggregator) Costs(bucket time.Duration, since time.Time) CostReport {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	stats := make(map[int64]*CostStat)
+	for _, c := range agg.costs.recent {
+		if c.At.Before(since) {
+			continue
+		}
+
+		start := c.At.Truncate(bucket).Unix()
+		stat, ok := stats[start]
+		if !ok {
+			s := NewCostStat()
+			stat = &s
+			stats[start] = stat
+		}
+		stat.add(c)
+	}
+
+	buckets := make([]CostBucket, 0, len(stats))
+	for start, stat := range stats {
+		stat.finalize()
+		buckets = append(buckets, CostBucket{Time: time.Unix(start, 0).UTC(), CostStat: *stat})
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Time.Before(buckets[j].Time)
+	})
+
+	total := agg.costs.total
+	total.finalize()
+
+	return CostReport{
+		Pair:    agg.Name(),
+		Bucket:  uint64(bucket / time.Second),
+		Total:   total,
+		Buckets: buckets,
+	}
+}
+
+// Returns the most recent relay transactions first, optionally of a single relayer
+func (agg *Aggregator) RelayCosts(relayer *common.Address, limit int) []RelayCost {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	costs := make([]RelayCost, 0)
+	for i := len(agg.costs.recent) - 1; i >= 0 && (limit == 0 || len(costs) < limit); i-- {
+		c := agg.costs.recent[i]
+		if relayer == nil || *relayer == c.Relayer {
+			costs = append(costs, *c)
+		}
+	}
+
+	return costs
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	DependencySetFile       = "file"       // dependency set JSON file, as in the superchain-registry
+	DependencySetSupervisor = "supervisor" // `supervisor_dependencySetV1` of the configured supervisor
+	DependencySetL1         = "l1"         // L1 system config, as relayed to the L1Block predeploy of a chain
+)
+
+// Predeploy the L1 system config updates with the dependency set of the chain
+var l1BlockAddress = common.HexToAddress("0x4200000000000000000000000000000000000015")
+
+var l1BlockABI = `[{"type":"function","name":"isInDependencySet","inputs":[{"name":"_chainId","type":"uint256"}],"outputs":[{"name":"","type":"bool"}],"stateMutability":"view"}]`
+
+// Where the chains to monitor are discovered. Every ordered pair of the discovered chains with an
+// RPC in `rpcs` is monitored, pairs are added and removed as the dependency set changes
+type DependencySetConfig struct {
+	Source      string `json:"source"` // "file", "supervisor" or "l1"
+	File        string `json:"file"`   // for the "file" source
+	Chain       uint64 `json:"chain"`  // chain whose L1Block is read, for the "l1" source
+	RefreshTime int    `json:"refreshTime"`
+}
+
+func validateDependencySet(config *Config) error {
+	set := config.DependencySet
+
+	if set == nil {
+		if config.SenderChain == "" || config.ReceiverChain == "" {
+			return fmt.Errorf("senderChain and receiverChain are required without dependencySet")
+		}
+		return nil
+	}
+
+	if (config.SenderChain == "") != (config.ReceiverChain == "") {
+		return fmt.Errorf("senderChain and receiverChain must be set together")
+	}
+
+	if len(config.RPCs) == 0 {
+		return fmt.Errorf("rpcs are required with dependencySet")
+	}
+
+	switch set.Source {
+	case DependencySetFile:
+		if set.File == "" {
+			return fmt.Errorf("dependencySet: file is required for the %q source", set.Source)
+		}
+	case DependencySetSupervisor:
+		if config.SupervisorURL == "" {
+			return fmt.Errorf("dependencySet: supervisorURL is required for the %q source", set.Source)
+		}
+	case DependencySetL1:
+		if _, ok := config.RPCs[set.Chain]; !ok {
+			return fmt.Errorf("dependencySet: chain must have an RPC in rpcs for the %q source", set.Source)
+		}
+	default:
+		return fmt.Errorf("dependencySet: source must be %q, %q or %q", DependencySetFile, DependencySetSupervisor, DependencySetL1)
+	}
+
+	if set.RefreshTime == 0 {
+		set.RefreshTime = 300
+	}
+	if set.RefreshTime < 1 {
+		return fmt.Errorf("dependencySet: refreshTime must be at least 1")
+	}
+
+	return nil
+}
+
+// Returns the chain ids of the dependency set
+type DependencySource interface {
+	ChainIds(ctx context.Context) ([]uint64, error)
+}
+
+func NewDependencySource(config *Config, supervisor Supervisor, chain func(uint64) (*Chain, error)) (DependencySource, error) {
+	switch config.DependencySet.Source {
+	case DependencySetFile:
+		return fileDependencySource(config.DependencySet.File), nil
+	case DependencySetSupervisor:
+		return supervisorDependencySource{supervisor}, nil
+	default:
+		parsed, err := abi.JSON(strings.NewReader(l1BlockABI))
+		if err != nil {
+			return nil, err
+		}
+		return &l1DependencySource{abi: &parsed, chain: chain, reference: config.DependencySet.Chain, candidates: config.RPCs}, nil
+	}
+}
+
+// Dependency set JSON, keyed by chain id, as read by op-supervisor
+type dependencySetFile struct {
+	Dependencies map[string]json.RawMessage `json:"dependencies"`
+}
+
+func (f dependencySetFile) chainIds() ([]uint64, error) {
+	ids := make([]uint64, 0, len(f.Dependencies))
+	for key := range f.Dependencies {
+		id, err := strconv.ParseUint(key, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chain id %q", key)
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, nil
+}
+
+// Read again on every refresh, so edits of the file are picked up
+type fileDependencySource string
+
+func (path fileDependencySource) ChainIds(ctx context.Context) ([]uint64, error) {
+	data, err := os.ReadFile(string(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var set dependencySetFile
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return set.chainIds()
+}
+
+type supervisorDependencySource struct {
+	supervisor Supervisor
+}
+
+func (s supervisorDependencySource) ChainIds(ctx context.Context) ([]uint64, error) {
+	return s.supervisor.DependencySet(ctx)
+}
+
+// The L1Block predeploy only answers whether a chain is in the dependency set, so the chains of
+// `rpcs` are the candidates
+type l1DependencySource struct {
+	abi        *abi.ABI
+	chain      func(uint64) (*Chain, error)
+	reference  uint64
+	candidates map[uint64]string
+}
+
+func (s *l1DependencySource) ChainIds(ctx context.Context) ([]uint64, error) {
+	chain, err := s.chain(s.reference)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]uint64, 0, len(s.candidates))
+	inputs := make([][]byte, 0, len(s.candidates))
+	for id := range s.candidates {
+		input, err := s.abi.Pack("isInDependencySet", new(big.Int).SetUint64(id))
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, id)
+		inputs = append(inputs, input)
+	}
+
+	outputs, errs, err := chain.BatchCall(l1BlockAddress, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uint64{s.reference}
+	for i, output := range outputs {
+		if candidates[i] == s.reference {
+			continue
+		}
+
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to check chain %d with the L1Block of %d: %w", candidates[i], s.reference, errs[i])
+		}
+
+		values, err := s.abi.Unpack("isInDependencySet", output)
+		if err != nil || len(values) != 1 {
+			return nil, fmt.Errorf("failed to check chain %d with the L1Block of %d", candidates[i], s.reference)
+		}
+		if in, _ := values[0].(bool); in {
+			ids = append(ids, candidates[i])
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, nil
+}
+
+// Refreshes the dependency set every `refreshTime` seconds
+func (m *Monitor) WatchDependencySet(source DependencySource) {
+	refresh := time.Second * time.Duration(m.config.DependencySet.RefreshTime)
+
+	for {
+		if err := m.Discover(source); err != nil {
+			m.errChan <- fmt.Errorf("dependency set: %w", err)
+		}
+
+		time.Sleep(refresh)
+	}
+}
+
+// Monitors every ordered pair of the chains of the dependency set with an RPC, and stops
+// monitoring the discovered pairs that left it
+func (m *Monitor) Discover(source DependencySource) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ids, err := source.ChainIds(ctx)
+	if err != nil {
+		return err
+	}
+	// an empty set is more likely a broken source than every chain leaving at once
+	if len(ids) == 0 {
+		return fmt.Errorf("empty dependency set")
+	}
+
+	var chains []*Chain
+	unreachable := make(map[uint64]bool) // their pairs are kept until they can be reached again
+	for _, id := range ids {
+		if _, ok := m.config.RPCs[id]; !ok {
+			m.errChan <- fmt.Errorf("dependency set: no RPC for chain %d", id)
+			continue
+		}
+
+		chain, err := m.chain(id)
+		if err != nil {
+			unreachable[id] = true
+			m.errChan <- fmt.Errorf("dependency set: chain %d: %w", id, err)
+			continue
+		}
+		chains = append(chains, chain)
+	}
+
+	wanted := make(map[string]ContractPair)
+	for _, sender := range chains {
+		for _, receiver := range chains {
+			if sender != receiver {
+				cp := ContractPair{Sender: sender, Receiver: receiver, Supervisor: m.supervisor}
+				wanted[cp.Name()] = cp
+			}
+		}
+	}
+
+	for _, agg := range m.discovered() {
+		if unreachable[agg.Sender.ChainId.Uint64()] || unreachable[agg.Receiver.ChainId.Uint64()] {
+			continue
+		}
+		if _, ok := wanted[agg.Name()]; !ok {
+			m.Remove(agg.Name())
+		}
+	}
+
+	names := make([]string, 0, len(wanted))
+	for name := range wanted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := m.Pair(name); err == nil {
+			continue
+		}
+		if err := m.Add(wanted[name], true); err != nil {
+			m.errChan <- fmt.Errorf("dependency set: pair %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
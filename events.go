@@ -0,0 +1,148 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	EventSent       = "sent"       // SentMessage seen on the sender
+	EventExecuting  = "executing"  // ExecutingMessage seen on the receiver
+	EventRelayed    = "relayed"    // RelayedMessage seen on the receiver
+	EventPaired     = "paired"     // sent and executing messages matched
+	EventExpired    = "expired"    // sent message purged without reception
+	EventFailed     = "failed"     // execution transaction reverted on the receiver
+	EventReconciled = "reconciled" // relay missed by log ingestion, found with successfulMessages
+	EventGap        = "gap"        // nonces skipped by the SentMessage logs of the sender
+	EventReplay     = "replay"     // message hash relayed again by another transaction
+	EventAlert      = "alert"      // alert state transition
+)
+
+// buffered events per subscriber, slow subscribers miss events instead of blocking the aggregator
+const subscriberBufferSize = 256
+
+type ExecutingEventData struct {
+	Id          Identifier  `json:"id"` // identifier of the sent message being executed
+	BlockNumber uint64      `json:"blockNumber"`
+	TxHash      common.Hash `json:"txHash"`
+}
+
+type RelayedEventData struct {
+	Source       uint64      `json:"source"`
+	MessageNonce *big.Int    `json:"messageNonce"`
+	MessageHash  common.Hash `json:"messageHash"`
+	BlockNumber  uint64      `json:"blockNumber"`
+	TxHash       common.Hash `json:"txHash"`
+}
+
+type StreamEvent struct {
+	Type   string      `json:"type"`
+	Pair   string      `json:"pair"`
+	Time   time.Time   `json:"time"`
+	Target string      `json:"target,omitempty"` // target contract of the message, when known
+	Data   interface{} `json:"data"`
+}
+
+// Filters for a stream subscription, empty sets match everything
+type StreamFilter struct {
+	Pairs   map[string]bool
+	Types   map[string]bool
+	Targets map[string]bool // lowercase hex addresses
+}
+
+// Parses comma separated lists of values
+func NewStreamFilter(pairs, types, targets string) StreamFilter {
+	set := func(list string, lower bool) map[string]bool {
+		values := make(map[string]bool)
+		for _, v := range strings.Split(list, ",") {
+			v = strings.TrimSpace(v)
+			if lower {
+				v = strings.ToLower(v)
+			}
+			if v != "" {
+				values[v] = true
+			}
+		}
+		return values
+	}
+
+	return StreamFilter{
+		Pairs:   set(pairs, false),
+		Types:   set(types, false),
+		Targets: set(targets, true),
+	}
+}
+
+func (f StreamFilter) Matches(ev StreamEvent) bool {
+	return (len(f.Pairs) == 0 || f.Pairs[ev.Pair]) &&
+		(len(f.Types) == 0 || f.Types[ev.Type]) &&
+		(len(f.Targets) == 0 || f.Targets[strings.ToLower(ev.Target)])
+}
+
+type Subscriber struct {
+	Events  chan StreamEvent
+	filter  StreamFilter
+	dropped atomic.Uint64
+}
+
+func (s *Subscriber) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Fans out the events processed by the aggregators to the stream subscribers
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[*Subscriber]struct{}
+}
+
+var eventBus = &EventBus{subs: make(map[*Subscriber]struct{})}
+
+func (b *EventBus) Subscribe(filter StreamFilter) *Subscriber {
+	sub := &Subscriber{
+		Events: make(chan StreamEvent, subscriberBufferSize),
+		filter: filter,
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+func (b *EventBus) Unsubscribe(sub *Subscriber) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	b.mu.Unlock()
+}
+
+// Never blocks
+func (b *EventBus) Publish(eventType, pair, target string, data interface{}) {
+	ev := StreamEvent{
+		Type:   eventType,
+		Pair:   pair,
+		Time:   time.Now().UTC(),
+		Target: target,
+		Data:   data,
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if !sub.filter.Matches(ev) {
+			continue
+		}
+
+		select {
+		case sub.Events <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
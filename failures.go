@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// receiver blocks scanned per iteration, so catching up doesn't starve the other watchers
+	failureScanBlocks = 100
+	// a block failing more, e.g. with pruned receipts, is skipped instead of stalling the receiver
+	failureMaxAttempts = 3
+
+	ReasonOutOfGas = "OutOfGas"
+	ReasonUnknown  = "unknown"
+	// raced by another relayer, doesn't delay the message
+	ReasonAlreadyRelayed = "MessageAlreadyRelayed"
+)
+
+// A reverted transaction sent to the CrossL2Inbox or the messenger on the receiver
+type ExecutionFailure struct {
+	TxHash    common.Hash    `json:"txHash"`
+	Block     uint64         `json:"block"`
+	At        time.Time      `json:"at"` // block time
+	Relayer   common.Address `json:"relayer"`
+	To        common.Address `json:"to"`
+	Method    string         `json:"method"`              // called function, empty if unknown
+	MessageId *Identifier    `json:"messageId,omitempty"` // sent message the transaction tried to execute
+	Reason    string         `json:"reason"`              // custom error name, revert string, or "OutOfGas"
+	GasUsed   uint64         `json:"gasUsed"`
+}
+
+// Keeps the last `size` failures, and the counts by reason since the monitor started
+type FailureTracker struct {
+	recent   []*ExecutionFailure // oldest first
+	size     int
+	total    uint64
+	byReason map[string]uint64
+	window   map[string]uint64 // since the last alert check
+}
+
+func NewFailureTracker(size int) *FailureTracker {
+	return &FailureTracker{
+		size:     size,
+		byReason: make(map[string]uint64),
+		window:   make(map[string]uint64),
+	}
+}
+
+func (t *FailureTracker) add(failure *ExecutionFailure) {
+	t.total++
+	t.byReason[failure.Reason]++
+	t.window[failure.Reason]++
+
+	t.recent = append(t.recent, failure)
+	if len(t.recent) > t.size {
+		t.recent = t.recent[len(t.recent)-t.size:]
+	}
+}
+
+// Counts the failures since the last check, apart from the ones raced by another relayer
+func (t *FailureTracker) check(config *Config, pair string) AlertCheck {
+	window := t.window
+	t.window = make(map[string]uint64)
+
+	var count uint64
+	var reasons []string
+	for reason, n := range window {
+		if reason == ReasonAlreadyRelayed {
+			continue
+		}
+		count += n
+		reasons = append(reasons, fmt.Sprintf("%s: %d", reason, n))
+	}
+	sort.Strings(reasons)
+
+	value := fmt.Sprintf("%d", count)
+	if len(reasons) > 0 {
+		value += " (" + strings.Join(reasons, ", ") + ")"
+	}
+
+	return AlertCheck{
+		Rule:      "failedExecutions",
+		Type:      "Failed Executions",
+		Severity:  SeverityWarning,
+		Pair:      pair,
+		Firing:    count > config.AlertFailedExecutionsMin,
+		Value:     value,
+		Threshold: fmt.Sprintf("%d", config.AlertFailedExecutionsMin),
+	}
+}
+
+// Decodes the called function and the identifier of the message it executes
+func decodeExecution(contractABI *abi.ABI, input []byte) (method string, id *Identifier) {
+	if len(input) < 4 {
+		return "", nil
+	}
+
+	m, err := contractABI.MethodById(input[:4])
+	if err != nil {
+		return "", nil
+	}
+
+	args := make(map[string]interface{})
+	if err := m.Inputs.UnpackIntoMap(args, input[4:]); err != nil {
+		return m.Name, nil
+	}
+
+	if value, ok := args["_id"]; ok {
+		if decoded, err := coerceToIdentifier(value); err == nil {
+			id = &decoded
+		}
+	}
+
+	return m.Name, id
+}
+
+// Matches the revert data with the custom errors of the contract, or decodes a revert string
+func decodeRevert(contractABI *abi.ABI, data []byte) string {
+	if len(data) < 4 {
+		return ReasonUnknown
+	}
+
+	for name, e := range contractABI.Errors {
+		if bytes.Equal(e.ID[:4], data[:4]) {
+			return name
+		}
+	}
+
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason
+	}
+
+	return fmt.Sprintf("%s (0x%x)", ReasonUnknown, data[:4])
+}
+
+// Scans a receiver for the failures of every pair executing on it, so the pairs sharing a
+// receiver don't each scan and replay its blocks
+type failureScanner struct {
+	pairs map[*Aggregator]struct{}
+	stop  chan struct{} // closed when the last pair leaves
+}
+
+// Scanners by receiver chain id, and their pairs
+var failureScanners = struct {
+	mu      sync.Mutex
+	byChain map[uint64]*failureScanner
+}{byChain: make(map[uint64]*failureScanner)}
+
+// Reverted transactions emit no logs, so the receiver blocks are scanned for transactions
+// sent to the CrossL2Inbox or the messenger, and the failed ones are replayed to get their reason.
+// The first pair of a receiver starts its scanner, which stops with the last one
+func (agg *Aggregator) WatchFailures(errChan chan error) {
+	chainId := agg.Receiver.ChainId.Uint64()
+
+	failureScanners.mu.Lock()
+	s, ok := failureScanners.byChain[chainId]
+	if !ok {
+		s = &failureScanner{pairs: make(map[*Aggregator]struct{}), stop: make(chan struct{})}
+		failureScanners.byChain[chainId] = s
+	}
+	s.pairs[agg] = struct{}{}
+	failureScanners.mu.Unlock()
+
+	if !ok {
+		go s.run(chainId, agg.Receiver, errChan)
+	}
+
+	<-agg.done
+
+	failureScanners.mu.Lock()
+	delete(s.pairs, agg)
+	if len(s.pairs) == 0 {
+		delete(failureScanners.byChain, chainId)
+		close(s.stop)
+	}
+	failureScanners.mu.Unlock()
+}
+
+// Returns the pairs of the scanner by name, nil once it has none left
+func (s *failureScanner) subscribers() []*Aggregator {
+	failureScanners.mu.Lock()
+	defer failureScanners.mu.Unlock()
+
+	if len(s.pairs) == 0 {
+		return nil
+	}
+
+	pairs := make([]*Aggregator, 0, len(s.pairs))
+	for agg := range s.pairs {
+		pairs = append(pairs, agg)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name() < pairs[j].Name() })
+
+	return pairs
+}
+
+// Sleeps between iterations, returns false if the scanner was stopped meanwhile
+func (s *failureScanner) sleep() bool {
+	select {
+	case <-s.stop:
+		return false
+	case <-time.After(time.Second * time.Duration(FETCH_SLEEP_TIME)):
+		return true
+	}
+}
+
+func (s *failureScanner) run(chainId uint64, receiver *Chain, errChan chan error) {
+	var next uint64
+	for {
+		head, err := receiver.GetCurrentBlockNumber()
+		if err == nil {
+			next = head.Uint64()
+			break
+		}
+		errChan <- err
+		if !s.sleep() {
+			return
+		}
+	}
+
+	attempts := 0 // of the next block
+	for {
+		pairs := s.subscribers()
+		if pairs == nil {
+			return
+		}
+
+		head, err := receiver.GetCurrentBlockNumber()
+		if err != nil {
+			errChan <- err
+		}
+
+		for scanned := 0; err == nil && next <= head.Uint64() && scanned < failureScanBlocks; scanned++ {
+			if err = scanFailures(receiver, next, pairs); err == nil {
+				next, attempts = next+1, 0
+				continue
+			}
+
+			errChan <- fmt.Errorf("failures: chain %d block %d: %w", chainId, next, err)
+			if attempts++; attempts >= failureMaxAttempts {
+				log.Printf("failures: skipping chain %d block %d after %d attempts", chainId, next, attempts)
+				next, attempts = next+1, 0
+			}
+		}
+
+		if !s.sleep() {
+			return
+		}
+	}
+}
+
+// Records the failures of the block on the pair of the sender of their message. Failures whose
+// message couldn't be decoded are only recorded on the first pair, so they aren't counted twice
+func scanFailures(receiver *Chain, number uint64, pairs []*Aggregator) error {
+	block, err := receiver.GetRawBlock(number)
+	if err != nil {
+		return err
+	}
+
+	// the contracts are predeploys, the same for every pair of the receiver
+	contracts := map[common.Address]*abi.ABI{
+		pairs[0].inboxContract.Address:             pairs[0].inboxContract.ABI,
+		pairs[0].receiverMessengerContract.Address: pairs[0].receiverMessengerContract.ABI,
+	}
+
+	bySender := make(map[uint64]*Aggregator, len(pairs))
+	for _, agg := range pairs {
+		bySender[agg.Sender.ChainId.Uint64()] = agg
+	}
+
+	var txs []RawTransaction
+	var hashes []common.Hash
+	for _, tx := range block.Transactions {
+		if tx.To != nil && contracts[*tx.To] != nil {
+			txs = append(txs, tx)
+			hashes = append(hashes, tx.Hash)
+		}
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+
+	receipts, errs, err := receiver.GetReceipts(hashes)
+	if err != nil {
+		return err
+	}
+
+	// the block is scanned again on error, so nothing is recorded before every receipt is there
+	for i, tx := range txs {
+		if errs[i] != nil {
+			return fmt.Errorf("receipt %s: %w", tx.Hash.Hex(), errs[i])
+		}
+		if receipts[i] == nil {
+			return fmt.Errorf("missing receipt %s", tx.Hash.Hex())
+		}
+	}
+
+	for i, tx := range txs {
+		receipt := receipts[i]
+		if receipt.Status != 0 {
+			continue
+		}
+
+		contractABI := contracts[*tx.To]
+		failure := &ExecutionFailure{
+			TxHash:  tx.Hash,
+			Block:   number,
+			At:      time.Unix(int64(block.Timestamp), 0).UTC(),
+			Relayer: tx.From,
+			To:      *tx.To,
+			GasUsed: uint64(receipt.GasUsed),
+		}
+		failure.Method, failure.MessageId = decodeExecution(contractABI, tx.Input)
+
+		// messages of chains that aren't monitored are skipped
+		agg := pairs[0]
+		if failure.MessageId != nil {
+			if agg = bySender[failure.MessageId.ChainId]; agg == nil {
+				continue
+			}
+		}
+
+		if receipt.GasUsed >= tx.Gas {
+			failure.Reason = ReasonOutOfGas
+		} else {
+			// replayed on the state at the end of the block, which may differ from the state the
+			// transaction saw, in which case the call doesn't revert and the reason is unknown
+			data, err := receiver.ReplayTransaction(tx, number)
+			if err != nil {
+				log.Printf("failures: failed to replay %s: %v", tx.Hash.Hex(), err)
+			}
+			failure.Reason = decodeRevert(contractABI, data)
+		}
+
+		agg.AddExecutionFailure(failure, receipt)
+	}
+
+	return nil
+}
+
+func (agg *Aggregator) AddExecutionFailure(failure *ExecutionFailure, receipt *Receipt) {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	agg.failures.add(failure)
+	agg.relayers.failed(receipt)
+	agg.costs.observeFailed(receipt)
+
+	var target string
+	if failure.MessageId != nil {
+		if record := agg.messages.get(*failure.MessageId); record != nil {
+			record.Failures++
+			record.LastFailure = failure.Reason
+			target = record.Target.Hex()
+		}
+	}
+
+	eventBus.Publish(EventFailed, agg.Name(), target, *failure)
+
+	log.Printf("failures: %s reverted with %s", failure.TxHash.Hex(), failure.Reason)
+}
+
+type FailureReport struct {
+	Pair     string             `json:"pair"`
+	Total    uint64             `json:"total"` // since the monitor started
+	ByReason map[string]uint64  `json:"byReason"`
+	Items    []ExecutionFailure `json:"items"` // newest first
+}
+
+// Returns the recent failures newest first, optionally with a single reason
+func (agg *Aggregator) Failures(reason string, limit int) FailureReport {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	report := FailureReport{
+		Pair:     agg.Name(),
+		Total:    agg.failures.total,
+		ByReason: make(map[string]uint64, len(agg.failures.byReason)),
+		Items:    make([]ExecutionFailure, 0),
+	}
+	for r, n := range agg.failures.byReason {
+		report.ByReason[r] = n
+	}
+
+	for i := len(agg.failures.recent) - 1; i >= 0 && (limit == 0 || len(report.Items) < limit); i-- {
+		failure := agg.failures.recent[i]
+		if reason == "" || reason == failure.Reason {
+			report.Items = append(report.Items, *failure)
+		}
+	}
+
+	return report
+}
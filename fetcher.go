@@ -2,15 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var FETCH_SLEEP_TIME int
@@ -21,6 +26,7 @@ type Chain struct {
 	Client         *ethclient.Client
 	ChainId        *big.Int
 	timestampCache map[uint64]*big.Int
+	cacheMu        *sync.Mutex // chains are shared by the pairs of a dependency set
 }
 
 // Any contract, knows how to fetch events and decode them
@@ -28,19 +34,21 @@ type Contract struct {
 	ABI     *abi.ABI
 	Address common.Address
 	Chain   *Chain
+	Status  *FetchStatus
 }
 
 type Identifier struct {
-	Origin      common.Address
-	BlockNumber uint64
-	LogIndex    uint64
-	Timestamp   uint64
-	ChainId     uint64
+	Origin      common.Address `json:"origin"`
+	BlockNumber uint64         `json:"blockNumber"`
+	LogIndex    uint64         `json:"logIndex"`
+	Timestamp   uint64         `json:"timestamp"`
+	ChainId     uint64         `json:"chainId"`
 }
 
 type ContractPair struct {
-	Sender   *Chain
-	Receiver *Chain
+	Sender     *Chain
+	Receiver   *Chain
+	Supervisor Supervisor // nil unless a supervisor is configured
 }
 
 func FetcherInit(config *Config) (err error) {
@@ -58,6 +66,12 @@ func FetcherInit(config *Config) (err error) {
 		return
 	}
 
+	messageHashArguments, err = newMessageHashArguments()
+
+	if err != nil {
+		return
+	}
+
 	return nil
 }
 
@@ -77,14 +91,16 @@ func NewChain(RPC string) (c *Chain, err error) {
 		Client:         client,
 		ChainId:        chainId,
 		timestampCache: make(map[uint64]*big.Int),
+		cacheMu:        &sync.Mutex{},
 	}
 
 	return
 }
 
-func (c *Chain) FetchLogs(address common.Address, from *big.Int) (logs []types.Log, err error) {
+func (c *Chain) FetchLogs(address common.Address, from, to *big.Int) (logs []types.Log, err error) {
 	logs, err = c.Client.FilterLogs(context.Background(), ethereum.FilterQuery{
 		FromBlock: from,
+		ToBlock:   to,
 		Addresses: []common.Address{address},
 	})
 
@@ -120,7 +136,9 @@ func (c *Chain) SubscribeLogsNotification(address common.Address, from *big.Int,
 }
 
 func (c *Chain) GetBlockTimestamp(blockNumber *big.Int) (timestamp *big.Int, err error) {
+	c.cacheMu.Lock()
 	time, ok := c.timestampCache[blockNumber.Uint64()]
+	c.cacheMu.Unlock()
 
 	if ok {
 		return time, nil
@@ -132,19 +150,158 @@ func (c *Chain) GetBlockTimestamp(blockNumber *big.Int) (timestamp *big.Int, err
 		return nil, err
 	}
 
+	c.cacheMu.Lock()
 	c.timestampCache[blockNumber.Uint64()] = big.NewInt(int64(header.Time))
+	c.cacheMu.Unlock()
 
 	return big.NewInt(int64(header.Time)), nil
 }
 
+// Fields of `eth_getTransactionReceipt` used by the monitor, OP Stack receipts also include the L1 fee
+type Receipt struct {
+	TxHash            common.Hash     `json:"transactionHash"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	From              common.Address  `json:"from"`
+	To                *common.Address `json:"to"`
+	Status            hexutil.Uint64  `json:"status"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	EffectiveGasPrice *hexutil.Big    `json:"effectiveGasPrice"`
+	L1Fee             *hexutil.Big    `json:"l1Fee"`
+}
+
+// Fetches the receipts in a single batch request, fetched raw since go-ethereum's receipts
+// don't include the sender. Receipts that failed or don't exist yet are nil, the error of the
+// ones that failed is in errs
+func (c *Chain) GetReceipts(hashes []common.Hash) (receipts []*Receipt, errs []error, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	receipts = make([]*Receipt, len(hashes))
+	errs = make([]error, len(hashes))
+	batch := make([]rpc.BatchElem, len(hashes))
+	for i, hash := range hashes {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{hash},
+			Result: &receipts[i],
+		}
+	}
+
+	if err := c.Client.Client().BatchCallContext(ctx, batch); err != nil {
+		return nil, nil, err
+	}
+
+	for i := range batch {
+		if batch[i].Error != nil {
+			receipts[i] = nil
+			errs[i] = batch[i].Error
+		}
+	}
+
+	return receipts, errs, nil
+}
+
+// Fields of the transactions of `eth_getBlockByNumber` used by the monitor
+type RawTransaction struct {
+	Hash  common.Hash     `json:"hash"`
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"` // nil for contract creations
+	Gas   hexutil.Uint64  `json:"gas"`
+	Value *hexutil.Big    `json:"value"`
+	Input hexutil.Bytes   `json:"input"`
+}
+
+type RawBlock struct {
+	Number       hexutil.Uint64   `json:"number"`
+	Timestamp    hexutil.Uint64   `json:"timestamp"`
+	Transactions []RawTransaction `json:"transactions"`
+}
+
+// Fetched raw since go-ethereum can't decode the deposit transactions of OP Stack blocks
+func (c *Chain) GetRawBlock(number uint64) (block *RawBlock, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = c.Client.Client().CallContext(ctx, &block, "eth_getBlockByNumber", hexutil.EncodeUint64(number), true)
+	if err == nil && block == nil {
+		err = ethereum.NotFound
+	}
+
+	return
+}
+
+// Re-executes the transaction with `eth_call` on the state of the given block, and returns
+// its revert data. The revert data is nil when the call succeeds or reverts without data
+func (c *Chain) ReplayTransaction(tx RawTransaction, block uint64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	call := map[string]interface{}{
+		"from":  tx.From,
+		"to":    tx.To,
+		"gas":   tx.Gas,
+		"input": tx.Input,
+	}
+	if tx.Value != nil {
+		call["value"] = tx.Value
+	}
+
+	var result hexutil.Bytes
+	err := c.Client.Client().CallContext(ctx, &result, "eth_call", call, hexutil.EncodeUint64(block))
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			return hexutil.Decode(data)
+		}
+	}
+	// reverts without data are reported as plain errors
+	if err != nil && !strings.HasPrefix(err.Error(), "execution reverted") {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// Runs several `eth_call`s on the latest state in a single batch request, the output of the
+// calls that failed is nil and their error is in errs
+func (c *Chain) BatchCall(to common.Address, inputs [][]byte) (outputs []hexutil.Bytes, errs []error, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	outputs = make([]hexutil.Bytes, len(inputs))
+	errs = make([]error, len(inputs))
+	batch := make([]rpc.BatchElem, len(inputs))
+	for i, input := range inputs {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{map[string]interface{}{"to": to, "input": hexutil.Bytes(input)}, "latest"},
+			Result: &outputs[i],
+		}
+	}
+
+	if err := c.Client.Client().BatchCallContext(ctx, batch); err != nil {
+		return nil, nil, err
+	}
+
+	for i := range batch {
+		if batch[i].Error != nil {
+			outputs[i] = nil
+			errs[i] = batch[i].Error
+		}
+	}
+
+	return outputs, errs, nil
+}
+
 func (c *Chain) GetCurrentBlockNumber() (blockNum *big.Int, err error) {
 	b, err := c.Client.BlockNumber(context.Background())
 
 	return big.NewInt(int64(b)), err
 }
 
-func (c Contract) FetchLogs(from *big.Int) (logs []types.Log, err error) {
-	logs, err = c.Chain.FetchLogs(c.Address, from)
+func (c Contract) FetchLogs(from, to *big.Int) (logs []types.Log, err error) {
+	logs, err = c.Chain.FetchLogs(c.Address, from, to)
 	return
 }
 
@@ -152,24 +309,44 @@ func (c Contract) SubscribeLogsNotification(from *big.Int, logsChan chan<- types
 	return c.Chain.SubscribeLogsNotification(c.Address, from, logsChan, errChan)
 }
 
-func (c Contract) CreateFetchChannel(from *big.Int, errChan chan error) (logsChan chan types.Log) {
+func (c Contract) CreateFetchChannel(from *big.Int, errChan chan error, done <-chan struct{}) (logsChan chan types.Log) {
 	logsChan = make(chan types.Log)
 	lastFetch := from.Uint64()
 
 	go func() {
 		for {
-			logs, err := c.FetchLogs(big.NewInt(int64(lastFetch)))
+			// fetch up to the current head, so the cursor advances even when there are no logs
+			head, err := c.Chain.GetCurrentBlockNumber()
 
 			if err != nil {
+				c.Status.failure(err)
 				errChan <- err
+			} else if head.Uint64() < lastFetch {
+				c.Status.success(head.Uint64(), lastFetch)
 			} else {
-				for _, l := range logs {
-					lastFetch = max(lastFetch, l.BlockNumber+1)
-					logsChan <- l
+				logs, err := c.FetchLogs(big.NewInt(int64(lastFetch)), head)
+
+				if err != nil {
+					c.Status.failure(err)
+					errChan <- err
+				} else {
+					for _, l := range logs {
+						select {
+						case logsChan <- l:
+						case <-done:
+							return
+						}
+					}
+					lastFetch = head.Uint64() + 1
+					c.Status.success(head.Uint64(), lastFetch)
 				}
 			}
 
-			time.Sleep(time.Second * time.Duration(FETCH_SLEEP_TIME))
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Second * time.Duration(FETCH_SLEEP_TIME)):
+			}
 		}
 	}()
 
@@ -190,6 +367,18 @@ func (c Contract) ParseEventToDic(eventLog types.Log) (eventName string, logData
 		return "", nil, err
 	}
 
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+
+	err = abi.ParseTopicsIntoMap(logData, indexed, eventLog.Topics[1:])
+	if err != nil {
+		return "", nil, err
+	}
+
 	return event.Name, logData, nil
 }
 
@@ -209,66 +398,77 @@ func (chain *Chain) GetEventIdentifier(eventLog types.Log) (id Identifier, err e
 	return
 }
 
-func (cp ContractPair) GetContracts() (inbox Contract, messenger Contract) {
+// Identifies the pair on the API and on alerts, as "<sender chain id>-<receiver chain id>"
+func (cp ContractPair) Name() string {
+	return fmt.Sprintf("%s-%s", cp.Sender.ChainId, cp.Receiver.ChainId)
+}
+
+// The messenger on the receiver emits RelayedMessage for the messages it delivers
+func (cp ContractPair) GetContracts() (inbox Contract, messenger Contract, receiverMessenger Contract) {
 	inbox = Contract{
 		ABI:     CrossL2InboxABI,
 		Address: common.HexToAddress("0x4200000000000000000000000000000000000022"),
 		Chain:   cp.Receiver,
+		Status:  NewFetchStatus(),
 	}
 
 	messenger = Contract{
 		ABI:     L2ToL2CrossDomainMessengerABI,
 		Address: common.HexToAddress("0x4200000000000000000000000000000000000023"),
 		Chain:   cp.Sender,
+		Status:  NewFetchStatus(),
+	}
+
+	receiverMessenger = Contract{
+		ABI:     L2ToL2CrossDomainMessengerABI,
+		Address: messenger.Address,
+		Chain:   cp.Receiver,
+		Status:  NewFetchStatus(),
 	}
 
 	return
 }
 
-func (cp ContractPair) FetchAggregateCycle(config *Config) (agg Aggregator, errChan chan error, err error) {
+// Starts monitoring the pair, errors of the goroutines are sent to errChan until the aggregator is stopped
+func (cp ContractPair) FetchAggregateCycle(config *Config, errChan chan error) (agg Aggregator, err error) {
 	agg = MakeAggregator(cp.Sender, cp.Receiver, config)
+	agg.supervisorClient = cp.Supervisor
 
-	errChan = make(chan error)
-
-	inbox, messenger := agg.inboxContract, agg.messengerContract
+	inbox, messenger, receiverMessenger := agg.inboxContract, agg.messengerContract, agg.receiverMessengerContract
 
 	inboxCurrentBlock, err := cp.Receiver.GetCurrentBlockNumber()
 
 	if err != nil {
-		return agg, nil, err
+		agg.Stop()
+		return agg, err
 	}
 
 	messengerCurrentBlock, err := cp.Sender.GetCurrentBlockNumber()
 
 	if err != nil {
-		return agg, nil, err
+		agg.Stop()
+		return agg, err
 	}
 
-	inboxChan := inbox.CreateFetchChannel(inboxCurrentBlock, errChan)
-	if err != nil {
-		return agg, nil, err
-	}
-
-	messengerChan := messenger.CreateFetchChannel(messengerCurrentBlock, errChan)
-	if err != nil {
-		return agg, nil, err
-	}
+	inboxChan := inbox.CreateFetchChannel(inboxCurrentBlock, errChan, agg.done)
+	messengerChan := messenger.CreateFetchChannel(messengerCurrentBlock, errChan, agg.done)
+	receiverMessengerChan := receiverMessenger.CreateFetchChannel(inboxCurrentBlock, errChan, agg.done)
 
 	// We read from both channels and log the events
 	go func() {
 		for {
 			select {
 			case inboxLog := <-inboxChan:
-				err := agg.AddInboxMessage(&inboxLog)
-				if err != nil {
-					errChan <- err
-				}
+				agg.ingest(func() error { return agg.AddInboxMessage(&inboxLog) }, errChan)
 			case messengerLog := <-messengerChan:
-				err := agg.AddMessengerMessage(&messengerLog)
+				agg.ingest(func() error { return agg.AddMessengerMessage(&messengerLog) }, errChan)
+			case relayLog := <-receiverMessengerChan:
+				err := agg.AddRelayedMessage(&relayLog)
 				if err != nil {
 					errChan <- err
 				}
-
+			case <-agg.done:
+				return
 			}
 		}
 
@@ -280,26 +480,46 @@ func (cp ContractPair) FetchAggregateCycle(config *Config) (agg Aggregator, errC
 			stats := agg.AggregateLatestBlocks(config.AggregateBlockAmount)
 
 			// detect alerts
-			if config.AlertAvgLatencyMin != 0 && stats.AvgLatency > config.AlertAvgLatencyMin {
-				SendAlert("Average Latency", fmt.Sprintf("%f", stats.AvgLatency), stats, config)
-			}
-
-			if config.AlertMissingReceptionMin != 0 && stats.MissingReception > config.AlertMissingReceptionMin {
-				SendAlert("Missing Reception", fmt.Sprintf("%d", stats.MissingReception), stats, config)
-			}
-
-			if config.AlertMissingRelayMin != 0 && stats.MissingRelay > config.AlertMissingRelayMin {
-				SendAlert("Missing Relay", fmt.Sprintf("%d", stats.MissingRelay), stats, config)
+			for _, check := range agg.AlertChecks(stats) {
+				if err := alertManager.Evaluate(check, stats); err != nil {
+					errChan <- err
+				}
 			}
 
-			// Custom alerts can be added here
+			agg.aggregated()
 
-			latest := *agg.LatestBlock
-			for *agg.LatestBlock < latest+config.AggregateBlockAmount {
-				time.Sleep(time.Duration(config.FetchTime))
+			latest := agg.latestBlock()
+			for agg.latestBlock() < latest+config.AggregateBlockAmount {
+				agg.aggregated()
+				if !agg.sleep(time.Second * time.Duration(config.FetchTime)) {
+					return
+				}
 			}
 		}
 	}()
 
+	// Liveness alerts are checked on a timer, since traffic may have stopped altogether
+	go agg.WatchLiveness(errChan)
+
+	// Relayers are identified from the receipts of the executions, off the aggregation path
+	go agg.WatchRelayers()
+	go agg.WatchFailures(errChan)
+
+	if config.ReconcileTime > 0 {
+		go agg.WatchReconciliation(errChan)
+	}
+
+	if config.TrackInboxOrigins {
+		go agg.WatchOrigins()
+	}
+
+	if agg.supervisorClient != nil {
+		go agg.WatchSupervisor(errChan)
+	}
+
+	if len(config.SLOs) > 0 {
+		go agg.WatchSLOs(errChan)
+	}
+
 	return
 }
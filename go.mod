@@ -4,7 +4,10 @@ go 1.22.0
 
 require (
 	github.com/ethereum/go-ethereum v1.14.12
+	github.com/gorilla/websocket v1.4.2
+	github.com/graph-gophers/graphql-go v1.6.0
 	github.com/labstack/echo/v4 v4.13.3
+	golang.org/x/time v0.8.0
 )
 
 require (
@@ -22,7 +25,6 @@ require (
 	github.com/fsnotify/fsnotify v1.6.0 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
 	github.com/google/uuid v1.3.0 // indirect
-	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/holiman/uint256 v1.3.1 // indirect
 	github.com/labstack/gommon v0.4.2 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
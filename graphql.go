@@ -0,0 +1,790 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed schema.graphql
+var graphQLSchema string
+
+// Unsigned 64 bits integer scalar, GraphQL's Int is only 32 bits
+type Uint64 uint64
+
+func (Uint64) ImplementsGraphQLType(name string) bool {
+	return name == "Uint64"
+}
+
+func (u *Uint64) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case int32:
+		if v < 0 {
+			return fmt.Errorf("Uint64 can't be negative")
+		}
+		*u = Uint64(v)
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("Uint64 can't be negative")
+		}
+		*u = Uint64(v)
+	case float64:
+		if v < 0 || v != math.Trunc(v) || v > math.MaxUint64 {
+			return fmt.Errorf("Uint64 must be a positive integer")
+		}
+		*u = Uint64(v)
+	case string:
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid Uint64 %q", v)
+		}
+		*u = Uint64(parsed)
+	default:
+		return fmt.Errorf("invalid Uint64 type %T", input)
+	}
+
+	return nil
+}
+
+func timePtr(t time.Time) *graphql.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: t}
+}
+
+func stringPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type graphQLResolver struct {
+	pairs func() []*Aggregator // the pairs may change at runtime
+}
+
+func NewGraphQLSchema(pairs func() []*Aggregator) *graphql.Schema {
+	return graphql.MustParseSchema(graphQLSchema, &graphQLResolver{pairs: pairs},
+		graphql.MaxDepth(10),
+		graphql.MaxParallelism(10),
+	)
+}
+
+func (r *graphQLResolver) findPair(name *string) (*Aggregator, error) {
+	aggs := r.pairs()
+	if name == nil {
+		if len(aggs) != 1 {
+			return nil, errors.New("`pair` is required unless monitoring a single pair")
+		}
+		return aggs[0], nil
+	}
+
+	for _, agg := range aggs {
+		if agg.Name() == *name {
+			return agg, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown pair %q", *name)
+}
+
+func pageSize(first int32) (uint64, error) {
+	if first < 1 || first > maxPageLimit {
+		return 0, fmt.Errorf("`first` must be between 1 and %d", maxPageLimit)
+	}
+	return uint64(first), nil
+}
+
+func (r *graphQLResolver) Chains() []*chainResolver {
+	seen := make(map[string]bool)
+	chains := make([]*chainResolver, 0)
+
+	for _, agg := range r.pairs() {
+		for _, chain := range []*chainResolver{senderChain(agg), receiverChain(agg)} {
+			if id := chain.contract.Chain.ChainId.String(); !seen[id] {
+				seen[id] = true
+				chains = append(chains, chain)
+			}
+		}
+	}
+
+	return chains
+}
+
+func (r *graphQLResolver) Pairs() []*pairResolver {
+	aggs := r.pairs()
+	pairs := make([]*pairResolver, 0, len(aggs))
+	for _, agg := range aggs {
+		pairs = append(pairs, &pairResolver{agg})
+	}
+	return pairs
+}
+
+func (r *graphQLResolver) Pair(args struct{ Name string }) *pairResolver {
+	agg, err := r.findPair(&args.Name)
+	if err != nil {
+		return nil
+	}
+	return &pairResolver{agg}
+}
+
+type blockStatFilter struct {
+	FromBlock *Uint64
+	ToBlock   *Uint64
+	FromTime  *graphql.Time
+	ToTime    *graphql.Time
+}
+
+func (r *graphQLResolver) BlockStats(args struct {
+	Pair   *string
+	Filter *blockStatFilter
+	Bin    *Uint64
+	Order  string
+	First  int32
+	After  *string
+}) (*blockStatConnection, error) {
+	agg, err := r.findPair(args.Pair)
+	if err != nil {
+		return nil, err
+	}
+
+	q := NewBlockStatQuery()
+	q.Desc = args.Order == "DESC"
+
+	if q.Limit, err = pageSize(args.First); err != nil {
+		return nil, err
+	}
+
+	if args.Bin != nil {
+		if *args.Bin == 0 {
+			return nil, errors.New("`bin` must be positive")
+		}
+		q.Bin = uint64(*args.Bin)
+	}
+
+	if f := args.Filter; f != nil {
+		if f.FromBlock != nil {
+			q.From = uint64(*f.FromBlock)
+		}
+		if f.ToBlock != nil {
+			q.To = uint64(*f.ToBlock)
+		}
+		if f.FromTime != nil {
+			q.FromTime = uint64(max(f.FromTime.Unix(), 0))
+		}
+		if f.ToTime != nil {
+			q.ToTime = uint64(max(f.ToTime.Unix(), 0))
+		}
+	}
+
+	if args.After != nil {
+		cursor, err := strconv.ParseUint(*args.After, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid `after` cursor")
+		}
+		q.Cursor = &cursor
+	}
+
+	return &blockStatConnection{agg.BlockStatPage(q)}, nil
+}
+
+type messageFilter struct {
+	Pair             *string
+	SourceChain      *Uint64
+	DestinationChain *Uint64
+	Target           *string
+	Sender           *string
+	Status           *string
+	MinLatency       *Uint64
+	MaxLatency       *Uint64
+	SentAfter        *graphql.Time
+	SentBefore       *graphql.Time
+	ExecutedAfter    *graphql.Time
+	ExecutedBefore   *graphql.Time
+	FromBlock        *Uint64
+	ToBlock          *Uint64
+}
+
+func (f *messageFilter) matches(m *messageResolver) bool {
+	if f == nil {
+		return true
+	}
+
+	r := m.record
+	executedBetween := func(after, before *graphql.Time) bool {
+		return r.ExecutedAt != nil &&
+			(after == nil || !r.ExecutedAt.Before(after.Time)) &&
+			(before == nil || !r.ExecutedAt.After(before.Time))
+	}
+	latencyBetween := func(low, high *Uint64) bool {
+		return r.Latency != nil &&
+			(low == nil || *r.Latency >= uint64(*low)) &&
+			(high == nil || *r.Latency <= uint64(*high))
+	}
+
+	return (f.Pair == nil || *f.Pair == m.agg.Name()) &&
+		(f.SourceChain == nil || uint64(*f.SourceChain) == r.Id.ChainId) &&
+		(f.DestinationChain == nil || uint64(*f.DestinationChain) == m.agg.Receiver.ChainId.Uint64()) &&
+		(f.Target == nil || strings.EqualFold(*f.Target, r.Target.Hex())) &&
+		(f.Sender == nil || strings.EqualFold(*f.Sender, r.Sender.Hex())) &&
+		(f.Status == nil || strings.EqualFold(*f.Status, string(r.Status))) &&
+		((f.MinLatency == nil && f.MaxLatency == nil) || latencyBetween(f.MinLatency, f.MaxLatency)) &&
+		(f.SentAfter == nil || !r.SentAt.Before(f.SentAfter.Time)) &&
+		(f.SentBefore == nil || !r.SentAt.After(f.SentBefore.Time)) &&
+		((f.ExecutedAfter == nil && f.ExecutedBefore == nil) || executedBetween(f.ExecutedAfter, f.ExecutedBefore)) &&
+		(f.FromBlock == nil || r.SentBlock >= uint64(*f.FromBlock)) &&
+		(f.ToBlock == nil || r.SentBlock <= uint64(*f.ToBlock))
+}
+
+func (r *graphQLResolver) Messages(args struct {
+	Filter *messageFilter
+	Order  string
+	First  int32
+	After  *string
+}) (*messageConnection, error) {
+	limit, err := pageSize(args.First)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := make([]*messageResolver, 0)
+	for _, agg := range r.pairs() {
+		for _, record := range agg.Messages(MessageQuery{}) {
+			m := &messageResolver{agg: agg, record: record}
+			if args.Filter.matches(m) {
+				matching = append(matching, m)
+			}
+		}
+	}
+
+	desc := args.Order == "DESC"
+	sort.Slice(matching, func(i, j int) bool {
+		if desc {
+			return matching[i].cursor() > matching[j].cursor()
+		}
+		return matching[i].cursor() < matching[j].cursor()
+	})
+
+	conn := &messageConnection{totalCount: len(matching)}
+
+	nodes := matching
+	if args.After != nil {
+		start := sort.Search(len(matching), func(i int) bool {
+			if desc {
+				return matching[i].cursor() < *args.After
+			}
+			return matching[i].cursor() > *args.After
+		})
+		nodes = matching[start:]
+	}
+
+	if uint64(len(nodes)) > limit {
+		nodes = nodes[:limit]
+		conn.pageInfo.hasNextPage = true
+	}
+	if len(nodes) > 0 {
+		conn.pageInfo.endCursor = stringPtr(nodes[len(nodes)-1].cursor())
+	}
+	conn.nodes = nodes
+
+	return conn, nil
+}
+
+func (r *graphQLResolver) Breakdown(args struct {
+	Pair  *string
+	By    string
+	First int32
+}) (*breakdownResolver, error) {
+	agg, err := r.findPair(args.Pair)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := pageSize(args.First)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := agg.Breakdown(strings.ToLower(args.By), int(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return &breakdownResolver{report}, nil
+}
+
+func (r *graphQLResolver) Relayers(args struct {
+	Pair  *string
+	First int32
+}) (*relayersResolver, error) {
+	agg, err := r.findPair(args.Pair)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := pageSize(args.First)
+	if err != nil {
+		return nil, err
+	}
+
+	return &relayersResolver{agg.Relayers(int(limit))}, nil
+}
+
+func (r *graphQLResolver) Alerts(args struct {
+	Pair     *string
+	Severity *string
+}) []*alertResolver {
+	alerts := make([]*alertResolver, 0)
+
+	for _, alert := range alertManager.Active() {
+		if (args.Pair == nil || *args.Pair == alert.Pair) && (args.Severity == nil || *args.Severity == alert.Severity) {
+			alerts = append(alerts, &alertResolver{alert})
+		}
+	}
+
+	return alerts
+}
+
+type alertHistoryFilter struct {
+	From *graphql.Time
+	To   *graphql.Time
+	Rule *string
+	Pair *string
+}
+
+func (r *graphQLResolver) AlertHistory(args struct {
+	Filter *alertHistoryFilter
+	Last   int32
+}) ([]*alertEventResolver, error) {
+	if args.Last < 1 {
+		return nil, errors.New("`last` must be positive")
+	}
+
+	q := AlertHistoryQuery{Limit: int(args.Last)}
+	if f := args.Filter; f != nil {
+		if f.From != nil {
+			q.From = f.From.Time
+		}
+		if f.To != nil {
+			q.To = f.To.Time
+		}
+		if f.Rule != nil {
+			q.Rule = *f.Rule
+		}
+		if f.Pair != nil {
+			q.Pair = *f.Pair
+		}
+	}
+
+	events := make([]*alertEventResolver, 0)
+	for _, ev := range alertManager.History(q) {
+		events = append(events, &alertEventResolver{ev})
+	}
+
+	return events, nil
+}
+
+type pageInfoResolver struct {
+	endCursor   *string
+	hasNextPage bool
+}
+
+func (p pageInfoResolver) EndCursor() *string { return p.endCursor }
+func (p pageInfoResolver) HasNextPage() bool  { return p.hasNextPage }
+
+type chainResolver struct {
+	contract Contract
+}
+
+// The sender is tracked through the messenger, the receiver through the inbox
+func senderChain(agg *Aggregator) *chainResolver   { return &chainResolver{agg.messengerContract} }
+func receiverChain(agg *Aggregator) *chainResolver { return &chainResolver{agg.inboxContract} }
+
+func (c *chainResolver) ChainId() Uint64 { return Uint64(c.contract.Chain.ChainId.Uint64()) }
+func (c *chainResolver) Head() Uint64    { return Uint64(c.contract.Status.State().Head) }
+func (c *chainResolver) Cursor() Uint64  { return Uint64(c.contract.Status.State().Cursor) }
+
+func (c *chainResolver) LastSuccessfulFetch() *graphql.Time {
+	return timePtr(c.contract.Status.State().LastSuccess)
+}
+
+func (c *chainResolver) LastFetchError() *string {
+	return stringPtr(c.contract.Status.State().LastError)
+}
+
+type pairResolver struct {
+	agg *Aggregator
+}
+
+func (p *pairResolver) Name() string             { return p.agg.Name() }
+func (p *pairResolver) Sender() *chainResolver   { return senderChain(p.agg) }
+func (p *pairResolver) Receiver() *chainResolver { return receiverChain(p.agg) }
+
+func (p *pairResolver) Latest(args struct{ Blocks *Uint64 }) (*intervalStatResolver, error) {
+	config := p.agg.config
+	blocks := config.AggregateBlockAmount
+
+	if args.Blocks != nil {
+		blocks = uint64(*args.Blocks)
+		if blocks > 2*config.AggregateBlockAmount && config.PurgeOldBlocks {
+			return nil, errors.New("`blocks` too large; purgeOldBlocks is set to true")
+		}
+	}
+
+	return &intervalStatResolver{p.agg.AggregateLatestBlocks(blocks)}, nil
+}
+
+func (p *pairResolver) PendingMessages() int32 {
+	return int32(len(p.agg.Messages(MessageQuery{Status: MessagePending})))
+}
+
+func (p *pairResolver) Inflight() *inflightResolver {
+	return &inflightResolver{p.agg.Inflight()}
+}
+
+type inflightResolver struct {
+	report InflightReport
+}
+
+func (r *inflightResolver) Undecoded() Uint64 { return Uint64(r.report.Undecoded) }
+
+func (r *inflightResolver) Tokens() []*inflightTokenResolver {
+	tokens := make([]*inflightTokenResolver, len(r.report.Tokens))
+	for i := range r.report.Tokens {
+		tokens[i] = &inflightTokenResolver{r.report.Tokens[i]}
+	}
+	return tokens
+}
+
+type inflightTokenResolver struct {
+	token InflightToken
+}
+
+func (t *inflightTokenResolver) Token() string               { return t.token.Token.Hex() }
+func (t *inflightTokenResolver) Messages() Uint64            { return Uint64(t.token.Messages) }
+func (t *inflightTokenResolver) Amount() string              { return t.token.Amount.String() }
+func (t *inflightTokenResolver) ExpiredMessages() Uint64     { return Uint64(t.token.ExpiredMessages) }
+func (t *inflightTokenResolver) Expired() string             { return t.token.Expired.String() }
+func (t *inflightTokenResolver) OldestSentAt() *graphql.Time { return timePtr(t.token.OldestSentAt) }
+func (t *inflightTokenResolver) Threshold() *float64         { return t.token.Threshold }
+
+type intervalStatResolver struct {
+	stats DetailedIntervalStat
+}
+
+func (s *intervalStatResolver) MessageCount() Uint64     { return Uint64(s.stats.MessageCount) }
+func (s *intervalStatResolver) AvgLatency() float64      { return s.stats.AvgLatency }
+func (s *intervalStatResolver) SentMessages() Uint64     { return Uint64(s.stats.SentMesssages) }
+func (s *intervalStatResolver) ReceivedMessages() Uint64 { return Uint64(s.stats.ReceivedMessages) }
+func (s *intervalStatResolver) MissingRelay() Uint64     { return Uint64(s.stats.MissingRelay) }
+func (s *intervalStatResolver) MissingReception() Uint64 { return Uint64(s.stats.MissingReception) }
+
+type blockStatConnection struct {
+	page BlockStatPage
+}
+
+func (c *blockStatConnection) Nodes() []*blockStatResolver {
+	nodes := make([]*blockStatResolver, 0, len(c.page.Items))
+	for _, item := range c.page.Items {
+		nodes = append(nodes, &blockStatResolver{item})
+	}
+	return nodes
+}
+
+func (c *blockStatConnection) PageInfo() pageInfoResolver {
+	return pageInfoResolver{endCursor: c.page.NextCursor, hasNextPage: c.page.NextCursor != nil}
+}
+
+type blockStatResolver struct {
+	item BlockStatItem
+}
+
+func (b *blockStatResolver) Block() Uint64            { return Uint64(b.item.Block) }
+func (b *blockStatResolver) MessageCount() Uint64     { return Uint64(b.item.MessageCount) }
+func (b *blockStatResolver) AvgLatency() float64      { return b.item.AvgLatency }
+func (b *blockStatResolver) MissingMessages() Uint64  { return Uint64(b.item.MissingPart) }
+func (b *blockStatResolver) MissingReception() Uint64 { return Uint64(b.item.MissingReception) }
+func (b *blockStatResolver) MissingRelay() Uint64     { return Uint64(b.item.MissingRelay) }
+
+func (b *blockStatResolver) Timestamp() *graphql.Time {
+	if b.item.Timestamp == 0 {
+		return nil
+	}
+	return timePtr(time.Unix(int64(b.item.Timestamp), 0).UTC())
+}
+
+type messageConnection struct {
+	nodes      []*messageResolver
+	pageInfo   pageInfoResolver
+	totalCount int
+}
+
+func (c *messageConnection) Nodes() []*messageResolver  { return c.nodes }
+func (c *messageConnection) PageInfo() pageInfoResolver { return c.pageInfo }
+func (c *messageConnection) TotalCount() int32          { return int32(c.totalCount) }
+
+type messageResolver struct {
+	agg    *Aggregator
+	record MessageRecord
+}
+
+// Orders messages by sending time, zero padded so cursors compare as strings
+func (m *messageResolver) cursor() string {
+	id := m.record.Id
+	return fmt.Sprintf("%020d-%020d-%020d-%020d", id.Timestamp, id.ChainId, id.BlockNumber, id.LogIndex)
+}
+
+func (m *messageResolver) Pair() string             { return m.agg.Name() }
+func (m *messageResolver) SourceChain() Uint64      { return Uint64(m.record.Id.ChainId) }
+func (m *messageResolver) DestinationChain() Uint64 { return Uint64(m.agg.Receiver.ChainId.Uint64()) }
+func (m *messageResolver) Origin() string           { return m.record.Id.Origin.Hex() }
+func (m *messageResolver) SentBlock() Uint64        { return Uint64(m.record.SentBlock) }
+func (m *messageResolver) LogIndex() Uint64         { return Uint64(m.record.Id.LogIndex) }
+func (m *messageResolver) SentAt() graphql.Time     { return graphql.Time{Time: m.record.SentAt} }
+func (m *messageResolver) Sender() string           { return m.record.Sender.Hex() }
+func (m *messageResolver) Target() string           { return m.record.Target.Hex() }
+func (m *messageResolver) Status() string           { return strings.ToUpper(string(m.record.Status)) }
+
+func (m *messageResolver) ExecutedBlock() *Uint64 {
+	if m.record.ExecutedAt == nil {
+		return nil
+	}
+	v := Uint64(m.record.ExecutedBlock)
+	return &v
+}
+
+func (m *messageResolver) ExecutedAt() *graphql.Time {
+	if m.record.ExecutedAt == nil {
+		return nil
+	}
+	return timePtr(*m.record.ExecutedAt)
+}
+
+func (m *messageResolver) Latency() *Uint64 {
+	if m.record.Latency == nil {
+		return nil
+	}
+	v := Uint64(*m.record.Latency)
+	return &v
+}
+
+func (m *messageResolver) Relayer() *string {
+	if m.record.Relayer == nil {
+		return nil
+	}
+	return stringPtr(m.record.Relayer.Hex())
+}
+
+func (m *messageResolver) Failures() Uint64 { return Uint64(m.record.Failures) }
+
+func (m *messageResolver) LastFailure() *string {
+	if m.record.LastFailure == "" {
+		return nil
+	}
+	return stringPtr(m.record.LastFailure)
+}
+
+func (m *messageResolver) Nonce() *string {
+	if m.record.Nonce == nil {
+		return nil
+	}
+	return stringPtr(m.record.Nonce.String())
+}
+
+func (m *messageResolver) MessageHash() *string {
+	if m.record.MessageHash == nil {
+		return nil
+	}
+	return stringPtr(m.record.MessageHash.Hex())
+}
+
+func (m *messageResolver) Payload() *payloadResolver {
+	if m.record.Payload == nil {
+		return nil
+	}
+	return &payloadResolver{m.record.Payload}
+}
+
+func (m *messageResolver) ExecutionTx() *string {
+	if m.record.ExecutionTx == nil {
+		return nil
+	}
+	return stringPtr(m.record.ExecutionTx.Hex())
+}
+
+type payloadResolver struct {
+	payload *Payload
+}
+
+func (p *payloadResolver) Decoder() string    { return p.payload.Decoder }
+func (p *payloadResolver) Method() string     { return p.payload.Method }
+func (p *payloadResolver) Token() *string     { return addressPtr(p.payload.Token) }
+func (p *payloadResolver) From() *string      { return addressPtr(p.payload.From) }
+func (p *payloadResolver) Recipient() *string { return addressPtr(p.payload.Recipient) }
+
+func (p *payloadResolver) Amount() *string {
+	if p.payload.Amount == nil {
+		return nil
+	}
+	return stringPtr(p.payload.Amount.String())
+}
+
+func addressPtr(address *common.Address) *string {
+	if address == nil {
+		return nil
+	}
+	return stringPtr(address.Hex())
+}
+
+type breakdownResolver struct {
+	report BreakdownReport
+}
+
+func (b *breakdownResolver) Pair() string { return b.report.Pair }
+
+func (b *breakdownResolver) Nodes() []*breakdownStatResolver {
+	nodes := make([]*breakdownStatResolver, 0, len(b.report.Items))
+	for _, item := range b.report.Items {
+		nodes = append(nodes, &breakdownStatResolver{item})
+	}
+	return nodes
+}
+
+func (b *breakdownResolver) Other() *breakdownStatResolver {
+	return &breakdownStatResolver{b.report.Other}
+}
+
+type breakdownStatResolver struct {
+	stat BreakdownStat
+}
+
+func (s *breakdownStatResolver) Key() string             { return s.stat.Key }
+func (s *breakdownStatResolver) Sent() Uint64            { return Uint64(s.stat.Sent) }
+func (s *breakdownStatResolver) Relayed() Uint64         { return Uint64(s.stat.Relayed) }
+func (s *breakdownStatResolver) Expired() Uint64         { return Uint64(s.stat.Expired) }
+func (s *breakdownStatResolver) Reconciled() Uint64      { return Uint64(s.stat.Reconciled) }
+func (s *breakdownStatResolver) Pending() Uint64         { return Uint64(s.stat.Pending) }
+func (s *breakdownStatResolver) AvgLatency() float64     { return s.stat.AvgLatency }
+func (s *breakdownStatResolver) MaxLatency() Uint64      { return Uint64(s.stat.MaxLatency) }
+func (s *breakdownStatResolver) LastSeen() *graphql.Time { return timePtr(s.stat.LastSeen) }
+
+func (s *breakdownStatResolver) Amount() *string {
+	if s.stat.Amount == nil {
+		return nil
+	}
+	return stringPtr(s.stat.Amount.String())
+}
+
+type relayersResolver struct {
+	report RelayerReport
+}
+
+func (r *relayersResolver) Pair() string { return r.report.Pair }
+
+func (r *relayersResolver) Nodes() []*relayerStatResolver {
+	nodes := make([]*relayerStatResolver, 0, len(r.report.Items))
+	for _, item := range r.report.Items {
+		nodes = append(nodes, &relayerStatResolver{item})
+	}
+	return nodes
+}
+
+func (r *relayersResolver) Other() *relayerStatResolver {
+	return &relayerStatResolver{r.report.Other}
+}
+
+type relayerStatResolver struct {
+	stat RelayerStat
+}
+
+func (s *relayerStatResolver) Address() string         { return s.stat.Address }
+func (s *relayerStatResolver) Messages() Uint64        { return Uint64(s.stat.Messages) }
+func (s *relayerStatResolver) Transactions() Uint64    { return Uint64(s.stat.Transactions) }
+func (s *relayerStatResolver) Failed() Uint64          { return Uint64(s.stat.Failed) }
+func (s *relayerStatResolver) FailureRate() float64    { return s.stat.FailureRate }
+func (s *relayerStatResolver) GasUsed() Uint64         { return Uint64(s.stat.GasUsed) }
+func (s *relayerStatResolver) L1Fee() string           { return s.stat.L1Fee.String() }
+func (s *relayerStatResolver) Cost() string            { return s.stat.Cost.String() }
+func (s *relayerStatResolver) AvgLatency() float64     { return s.stat.AvgLatency }
+func (s *relayerStatResolver) MaxLatency() Uint64      { return Uint64(s.stat.MaxLatency) }
+func (s *relayerStatResolver) LastSeen() *graphql.Time { return timePtr(s.stat.LastSeen) }
+
+type alertResolver struct {
+	alert ActiveAlert
+}
+
+func (a *alertResolver) Rule() string        { return a.alert.Rule }
+func (a *alertResolver) Type() string        { return a.alert.Type }
+func (a *alertResolver) Severity() string    { return a.alert.Severity }
+func (a *alertResolver) Pair() string        { return a.alert.Pair }
+func (a *alertResolver) Value() string       { return a.alert.Value }
+func (a *alertResolver) Threshold() string   { return a.alert.Threshold }
+func (a *alertResolver) Since() graphql.Time { return graphql.Time{Time: a.alert.Since} }
+func (a *alertResolver) Acknowledged() bool  { return a.alert.Acknowledged }
+func (a *alertResolver) Silenced() bool      { return a.alert.Silenced }
+
+func (a *alertResolver) AcknowledgedAt() *graphql.Time {
+	if a.alert.AcknowledgedAt == nil {
+		return nil
+	}
+	return timePtr(*a.alert.AcknowledgedAt)
+}
+
+type alertEventResolver struct {
+	ev AlertEvent
+}
+
+func (e *alertEventResolver) Time() graphql.Time           { return graphql.Time{Time: e.ev.Time} }
+func (e *alertEventResolver) Event() string                { return string(e.ev.Event) }
+func (e *alertEventResolver) Rule() string                 { return e.ev.Rule }
+func (e *alertEventResolver) Type() string                 { return e.ev.Type }
+func (e *alertEventResolver) Severity() string             { return e.ev.Severity }
+func (e *alertEventResolver) Pair() string                 { return e.ev.Pair }
+func (e *alertEventResolver) Value() string                { return e.ev.Value }
+func (e *alertEventResolver) Threshold() string            { return e.ev.Threshold }
+func (e *alertEventResolver) Stats() *intervalStatResolver { return &intervalStatResolver{e.ev.Stats} }
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Accepts `POST` JSON bodies, and `GET` requests with the same fields as query params
+func graphQLRoute(schema *graphql.Schema) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req graphQLRequest
+
+		if c.Request().Method == http.MethodGet {
+			req.Query = c.QueryParam("query")
+			req.OperationName = c.QueryParam("operationName")
+			if vars := c.QueryParam("variables"); vars != "" {
+				if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+					return c.String(http.StatusBadRequest, "Invalid `variables` value")
+				}
+			}
+		} else if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return c.String(http.StatusBadRequest, "Invalid GraphQL request body")
+		}
+
+		if req.Query == "" {
+			return c.String(http.StatusBadRequest, "`query` is required")
+		}
+
+		res := schema.Exec(c.Request().Context(), req.Query, req.OperationName, req.Variables)
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
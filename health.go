@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const rpcProbeTimeout = 3 * time.Second
+
+type ChainHealth struct {
+	Pair                  string    `json:"pair"`
+	Side                  string    `json:"side"`
+	ChainId               string    `json:"chainId"`
+	RPCReachable          bool      `json:"rpcReachable"`
+	RPCError              string    `json:"rpcError,omitempty"`
+	Head                  uint64    `json:"head"`
+	Cursor                uint64    `json:"cursor"`
+	HeadLag               uint64    `json:"headLag"`
+	LastSuccessfulFetch   time.Time `json:"lastSuccessfulFetch"`
+	SecondsSinceLastFetch float64   `json:"secondsSinceLastFetch"`
+	LastFetchError        string    `json:"lastFetchError,omitempty"`
+}
+
+type AlertEngineHealth struct {
+	LastEvaluation             time.Time `json:"lastEvaluation"`
+	SecondsSinceLastEvaluation float64   `json:"secondsSinceLastEvaluation"`
+	PendingDeliveries          int       `json:"pendingDeliveries"`
+}
+
+type Readiness struct {
+	Ready       bool              `json:"ready"`
+	Problems    []string          `json:"problems"`
+	Chains      []ChainHealth     `json:"chains"`
+	AlertEngine AlertEngineHealth `json:"alertEngine"`
+}
+
+type rpcProbe struct {
+	head uint64
+	err  error
+}
+
+// Asks every distinct chain for its head at once, so a report over many pairs costs a
+// single probe timeout
+func probeChains(chains ...*Chain) map[uint64]rpcProbe {
+	probes := make(map[uint64]rpcProbe)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, chain := range chains {
+		chainId := chain.ChainId.Uint64()
+		if _, ok := probes[chainId]; ok {
+			continue
+		}
+		probes[chainId] = rpcProbe{}
+
+		wg.Add(1)
+		go func(chain *Chain) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), rpcProbeTimeout)
+			defer cancel()
+
+			head, err := chain.Client.BlockNumber(ctx)
+
+			mu.Lock()
+			probes[chainId] = rpcProbe{head: head, err: err}
+			mu.Unlock()
+		}(chain)
+	}
+
+	wg.Wait()
+
+	return probes
+}
+
+func chainHealth(side string, contract Contract, probe rpcProbe, now time.Time) (h ChainHealth) {
+	state := contract.Status.State()
+
+	h.Side = side
+	h.ChainId = contract.Chain.ChainId.String()
+	h.Head = state.Head
+	h.Cursor = state.Cursor
+	h.LastSuccessfulFetch = state.LastSuccess
+	h.SecondsSinceLastFetch = now.Sub(state.LastSuccess).Seconds()
+	h.LastFetchError = state.LastError
+
+	if probe.err != nil {
+		h.RPCError = probe.err.Error()
+	} else {
+		h.RPCReachable = true
+		h.Head = max(h.Head, probe.head)
+	}
+
+	// the cursor is the next block to fetch
+	if h.Head+1 > h.Cursor {
+		h.HeadLag = h.Head + 1 - h.Cursor
+	}
+
+	return
+}
+
+func (agg *Aggregator) Readiness() Readiness {
+	return agg.readiness(probeChains(agg.Sender, agg.Receiver))
+}
+
+// Readiness of the pair, given the probes of its chains
+func (agg *Aggregator) readiness(probes map[uint64]rpcProbe) (r Readiness) {
+	config := agg.config
+	now := time.Now()
+	r.Problems = make([]string, 0)
+
+	r.Chains = []ChainHealth{
+		chainHealth("sender", agg.messengerContract, probes[agg.Sender.ChainId.Uint64()], now),
+		chainHealth("receiver", agg.inboxContract, probes[agg.Receiver.ChainId.Uint64()], now),
+	}
+	for i := range r.Chains {
+		r.Chains[i].Pair = agg.Name()
+	}
+
+	for _, h := range r.Chains {
+		if !h.RPCReachable {
+			r.Problems = append(r.Problems, fmt.Sprintf("%s RPC unreachable: %s", h.Side, h.RPCError))
+		}
+
+		if h.HeadLag > config.ReadyMaxHeadLag {
+			r.Problems = append(r.Problems, fmt.Sprintf("%s fetch cursor is %d blocks behind the head", h.Side, h.HeadLag))
+		}
+
+		if h.SecondsSinceLastFetch > float64(config.ReadyMaxFetchAge) {
+			r.Problems = append(r.Problems, fmt.Sprintf("%s last successful fetch was %.0fs ago", h.Side, h.SecondsSinceLastFetch))
+		}
+	}
+
+	r.AlertEngine.LastEvaluation = alertManager.LastEvaluation()
+	r.AlertEngine.SecondsSinceLastEvaluation = now.Sub(r.AlertEngine.LastEvaluation).Seconds()
+	r.AlertEngine.PendingDeliveries = len(alertOutbox.Deliveries(DeliveryPending))
+
+	// liveness alerts are evaluated on every liveness check, so a few missed ones mean the engine is stuck
+	if r.AlertEngine.SecondsSinceLastEvaluation > float64(3*config.LivenessCheckTime) {
+		r.Problems = append(r.Problems, fmt.Sprintf("alerts were last evaluated %.0fs ago", r.AlertEngine.SecondsSinceLastEvaluation))
+	}
+
+	r.Ready = len(r.Problems) == 0
+
+	return
+}
+
+func healthzRoute(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness of every pair, their problems prefixed with the pair
+func (m *Monitor) Readiness() (r Readiness) {
+	r.Problems = make([]string, 0)
+	r.Chains = make([]ChainHealth, 0)
+
+	aggs := m.Pairs()
+	if len(aggs) == 0 {
+		r.Problems = append(r.Problems, "no pair is monitored")
+	}
+
+	// the pairs of a dependency set share their chains
+	chains := make([]*Chain, 0, 2*len(aggs))
+	for _, agg := range aggs {
+		chains = append(chains, agg.Sender, agg.Receiver)
+	}
+	probes := probeChains(chains...)
+
+	for i, agg := range aggs {
+		pr := agg.readiness(probes)
+		if i == 0 {
+			r.AlertEngine = pr.AlertEngine
+		}
+
+		r.Chains = append(r.Chains, pr.Chains...)
+		for _, problem := range pr.Problems {
+			r.Problems = append(r.Problems, agg.Name()+": "+problem)
+		}
+	}
+
+	r.Ready = len(r.Problems) == 0
+
+	return
+}
+
+// Readiness of the `pair` query param, or of every pair. Probes are unauthenticated, so the
+// problems and chains, which hold RPC errors and URLs, are only returned to `read` keys
+func (m *Monitor) ReadyzRoute(c echo.Context) error {
+	name := c.QueryParam("pair")
+
+	var r Readiness
+	if agg, err := m.Pair(name); err == nil {
+		r = agg.Readiness()
+	} else if name != "" {
+		return c.String(http.StatusBadRequest, err.Error())
+	} else {
+		r = m.Readiness()
+	}
+
+	status := http.StatusOK
+	if !r.Ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	if !allowsScope(m.config, c, ScopeRead) {
+		return c.JSON(status, map[string]bool{"ready": r.Ready})
+	}
+
+	return c.JSON(status, r)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Decoded amounts of a token sent and not relayed yet
+type InflightToken struct {
+	Token           common.Address `json:"token"` // zero address for ETH
+	Messages        uint64         `json:"messages"`
+	Amount          *big.Int       `json:"amount"` // in the smallest unit of the token
+	ExpiredMessages uint64         `json:"expiredMessages"`
+	Expired         *big.Int       `json:"expired"` // part of the amount in expired messages
+	OldestSentAt    time.Time      `json:"oldestSentAt"`
+	Threshold       *float64       `json:"threshold,omitempty"` // of the inflightValue alert
+}
+
+type InflightReport struct {
+	Pair      string          `json:"pair"`
+	Tokens    []InflightToken `json:"tokens"`    // most messages in flight first
+	Undecoded uint64          `json:"undecoded"` // messages in flight without a decoded token and amount
+}
+
+// Sums the decoded amounts of the messages in flight by token: the pending ones, and the expired
+// ones among the last finished that weren't relayed or reconciled since, as pending messages
+// expire after 2*aggregateBlockAmount blocks, well before a stall is over. Tokens with an alert
+// threshold are always listed, so their value doesn't vanish when nothing is in flight
+func (agg *Aggregator) Inflight() InflightReport {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	report := InflightReport{Pair: agg.Name(), Tokens: make([]InflightToken, 0)}
+
+	tokens := make(map[common.Address]*InflightToken)
+	get := func(token common.Address) *InflightToken {
+		t, ok := tokens[token]
+		if !ok {
+			t = &InflightToken{Token: token, Amount: new(big.Int), Expired: new(big.Int)}
+			tokens[token] = t
+		}
+		return t
+	}
+
+	for token := range agg.config.AlertInflightValue {
+		get(token)
+	}
+
+	observe := func(record *MessageRecord) {
+		p := record.Payload
+		if p == nil || p.Token == nil || p.Amount == nil {
+			report.Undecoded++
+			return
+		}
+
+		t := get(*p.Token)
+		t.Messages++
+		t.Amount.Add(t.Amount, p.Amount)
+		if record.Status == MessageExpired {
+			t.ExpiredMessages++
+			t.Expired.Add(t.Expired, p.Amount)
+		}
+		if t.OldestSentAt.IsZero() || record.SentAt.Before(t.OldestSentAt) {
+			t.OldestSentAt = record.SentAt
+		}
+	}
+
+	for _, record := range agg.messages.pending {
+		observe(record)
+	}
+	for _, record := range agg.messages.finished {
+		if record.Status == MessageExpired {
+			observe(record)
+		}
+	}
+
+	for token, t := range tokens {
+		if threshold, ok := agg.config.AlertInflightValue[token]; ok {
+			t.Threshold = &threshold
+		}
+		report.Tokens = append(report.Tokens, *t)
+	}
+
+	sort.Slice(report.Tokens, func(i, j int) bool {
+		if report.Tokens[i].Messages != report.Tokens[j].Messages {
+			return report.Tokens[i].Messages > report.Tokens[j].Messages
+		}
+		return report.Tokens[i].Token.Hex() < report.Tokens[j].Token.Hex()
+	})
+
+	return report
+}
+
+// Fires when the value in flight of any token is above its threshold
+func (agg *Aggregator) inflightCheck(pair string) AlertCheck {
+	var values, thresholds []string
+	firing := false
+
+	for _, t := range agg.Inflight().Tokens {
+		if t.Threshold == nil {
+			continue
+		}
+
+		amount := weiFloat(t.Amount)
+		if amount > *t.Threshold {
+			firing = true
+		}
+		values = append(values, fmt.Sprintf("%s: %g", t.Token.Hex(), amount))
+		thresholds = append(thresholds, fmt.Sprintf("%s: %g", t.Token.Hex(), *t.Threshold))
+	}
+
+	return AlertCheck{
+		Rule:      "inflightValue",
+		Type:      "Value In Flight",
+		Severity:  SeverityWarning,
+		Pair:      pair,
+		Firing:    firing,
+		Value:     strings.Join(values, ", "),
+		Threshold: strings.Join(thresholds, ", "),
+	}
+}
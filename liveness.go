@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Progress of the fetch loop of a contract
+type FetchStatus struct {
+	mu               sync.Mutex
+	head             uint64
+	cursor           uint64
+	lastSuccess      time.Time
+	lastCursorChange time.Time
+	lastHeadChange   time.Time
+	lastError        string
+}
+
+// Point in time copy of a FetchStatus
+type FetchState struct {
+	Head             uint64    `json:"head"`
+	Cursor           uint64    `json:"cursor"`
+	LastSuccess      time.Time `json:"lastSuccess"`
+	LastCursorChange time.Time `json:"lastCursorChange"`
+	LastHeadChange   time.Time `json:"lastHeadChange"`
+	LastError        string    `json:"lastError,omitempty"`
+}
+
+func NewFetchStatus() *FetchStatus {
+	now := time.Now().UTC()
+
+	// nothing is considered stalled before the first fetch had a chance to happen
+	return &FetchStatus{
+		lastSuccess:      now,
+		lastCursorChange: now,
+		lastHeadChange:   now,
+	}
+}
+
+func (s *FetchStatus) success(head, cursor uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	if head != s.head {
+		s.head = head
+		s.lastHeadChange = now
+	}
+
+	if cursor != s.cursor {
+		s.cursor = cursor
+		s.lastCursorChange = now
+	}
+
+	s.lastSuccess = now
+	s.lastError = ""
+}
+
+func (s *FetchStatus) failure(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastError = err.Error()
+}
+
+func (s *FetchStatus) State() FetchState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return FetchState{
+		Head:             s.head,
+		Cursor:           s.cursor,
+		LastSuccess:      s.lastSuccess,
+		LastCursorChange: s.lastCursorChange,
+		LastHeadChange:   s.lastHeadChange,
+		LastError:        s.lastError,
+	}
+}
+
+// Checks that traffic keeps flowing and that the fetch loops keep advancing
+func (agg *Aggregator) LivenessChecks() (checks []AlertCheck) {
+	config := agg.config
+	pair := agg.Name()
+	now := time.Now()
+
+	if config.AlertNoMessagesMinutes != 0 {
+		idle := now.Sub(agg.LastMessageTime())
+		threshold := time.Duration(config.AlertNoMessagesMinutes) * time.Minute
+
+		checks = append(checks, AlertCheck{
+			Rule:      "noMessages",
+			Type:      "No Messages",
+			Severity:  SeverityCritical,
+			Pair:      pair,
+			Firing:    idle > threshold,
+			Value:     idle.Round(time.Second).String(),
+			Threshold: threshold.String(),
+		})
+	}
+
+	contracts := []struct {
+		side     string
+		contract Contract
+	}{
+		{"sender", agg.messengerContract},
+		{"receiver", agg.inboxContract},
+	}
+
+	if config.AlertFetchStallMinutes != 0 {
+		threshold := time.Duration(config.AlertFetchStallMinutes) * time.Minute
+		check := AlertCheck{
+			Rule:      "fetchStalled",
+			Type:      "Fetch Stalled",
+			Severity:  SeverityCritical,
+			Pair:      pair,
+			Value:     "0s",
+			Threshold: threshold.String(),
+		}
+
+		// the cursor is stalled if fetches fail, or if it stops following a moving head
+		for _, c := range contracts {
+			state := c.contract.Status.State()
+			stalled := now.Sub(state.LastSuccess)
+			if state.Cursor <= state.Head {
+				stalled = max(stalled, now.Sub(state.LastCursorChange))
+			}
+
+			if stalled > threshold {
+				check.Firing = true
+				check.Value = fmt.Sprintf("%s cursor at %d stalled for %s", c.side, state.Cursor, stalled.Round(time.Second))
+			}
+		}
+
+		checks = append(checks, check)
+	}
+
+	if config.AlertHeadStallMinutes != 0 {
+		threshold := time.Duration(config.AlertHeadStallMinutes) * time.Minute
+		check := AlertCheck{
+			Rule:      "headStalled",
+			Type:      "Chain Head Stalled",
+			Severity:  SeverityCritical,
+			Pair:      pair,
+			Value:     "0s",
+			Threshold: threshold.String(),
+		}
+
+		for _, c := range contracts {
+			state := c.contract.Status.State()
+
+			if stalled := now.Sub(state.LastHeadChange); stalled > threshold {
+				check.Firing = true
+				check.Value = fmt.Sprintf("%s head at %d stalled for %s", c.side, state.Head, stalled.Round(time.Second))
+			}
+		}
+
+		checks = append(checks, check)
+	}
+
+	return
+}
+
+// Evaluates the liveness alerts periodically, and pings the heartbeat URL while everything is healthy
+func (agg *Aggregator) WatchLiveness(errChan chan<- error) {
+	config := agg.config
+
+	for {
+		if !agg.sleep(time.Second * time.Duration(config.LivenessCheckTime)) {
+			return
+		}
+
+		stats := agg.AggregateLatestBlocks(config.AggregateBlockAmount)
+		healthy := true
+
+		for _, check := range agg.LivenessChecks() {
+			if check.Firing {
+				healthy = false
+			}
+
+			if err := alertManager.Evaluate(check, stats); err != nil {
+				errChan <- err
+			}
+		}
+
+		alertManager.MarkEvaluated()
+
+		if healthy && config.HeartbeatURL != "" {
+			if err := agg.pipelineStalled(); err != nil {
+				errChan <- fmt.Errorf("heartbeat skipped: %w", err)
+				continue
+			}
+
+			if err := pingHeartbeat(config.HeartbeatURL); err != nil {
+				errChan <- fmt.Errorf("heartbeat failed: %w", err)
+			}
+		}
+	}
+}
+
+// The liveness rules are optional, so the heartbeat also requires a recent successful fetch on both
+// chains and a recent pass of the aggregate loop, within `readyMaxFetchAge` seconds
+func (agg *Aggregator) pipelineStalled() error {
+	maxAge := time.Duration(agg.config.ReadyMaxFetchAge) * time.Second
+
+	contracts := []struct {
+		side     string
+		contract Contract
+	}{
+		{"sender", agg.messengerContract},
+		{"receiver", agg.inboxContract},
+	}
+
+	for _, c := range contracts {
+		if age := time.Since(c.contract.Status.State().LastSuccess); age > maxAge {
+			return fmt.Errorf("%s last successful fetch was %s ago", c.side, age.Round(time.Second))
+		}
+	}
+
+	if age := time.Since(agg.LastAggregationTime()); age > maxAge {
+		return fmt.Errorf("aggregate loop last ran %s ago", age.Round(time.Second))
+	}
+
+	return nil
+}
+
+func pingHeartbeat(url string) error {
+	client := http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
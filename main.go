@@ -23,21 +23,44 @@ func main() {
 	config, err := parseConfig(data)
 	must(err)
 
-	senderChain, err := NewChain(config.SenderChain)
-	must(err)
+	var supervisor Supervisor
+	if config.SupervisorURL != "" {
+		supervisor, err = NewSupervisorClient(config.SupervisorURL)
+		must(err)
+	}
 
-	receiverChain, err := NewChain(config.ReceiverChain)
+	err = FetcherInit(config)
 	must(err)
 
-	cp := ContractPair{Sender: senderChain, Receiver: receiverChain}
-
-	err = FetcherInit(config)
+	err = AlertOutboxInit(config)
 	must(err)
 
-	agg, errChan, err := cp.FetchAggregateCycle(config)
+	err = AlertManagerInit(config)
 	must(err)
 
-	go StartApi(config, &agg)
+	errChan := make(chan error)
+	monitor := NewMonitor(config, supervisor, errChan)
+
+	// the configured pair is always monitored, discovered pairs come and go with the dependency set
+	if config.SenderChain != "" {
+		senderChain, err := NewChain(config.SenderChain)
+		must(err)
+
+		receiverChain, err := NewChain(config.ReceiverChain)
+		must(err)
+
+		cp := ContractPair{Sender: senderChain, Receiver: receiverChain, Supervisor: supervisor}
+		must(monitor.Add(cp, false))
+	}
+
+	if config.DependencySet != nil {
+		source, err := NewDependencySource(config, supervisor, monitor.chain)
+		must(err)
+
+		go monitor.WatchDependencySet(source)
+	}
+
+	go StartApi(config, monitor)
 
 	for {
 		select {
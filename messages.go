@@ -0,0 +1,242 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type MessageStatus string
+
+const (
+	MessagePending MessageStatus = "pending"
+	MessageRelayed MessageStatus = "relayed"
+	MessageExpired MessageStatus = "expired" // purged before a relay was seen
+	// relayed according to `successfulMessages`, but the relay log was missed so the latency is unknown
+	MessageReconciled MessageStatus = "reconciled"
+)
+
+// Lifecycle of a single message sent by the messenger
+type MessageRecord struct {
+	Id            Identifier      `json:"id"`
+	Target        common.Address  `json:"target"`
+	Sender        common.Address  `json:"sender"`
+	Nonce         *big.Int        `json:"nonce,omitempty"`
+	MessageHash   *common.Hash    `json:"messageHash,omitempty"` // key of the message in `successfulMessages`
+	Status        MessageStatus   `json:"status"`
+	SentBlock     uint64          `json:"sentBlock"`
+	SentAt        time.Time       `json:"sentAt"`
+	ExecutedBlock uint64          `json:"executedBlock,omitempty"`
+	ExecutedAt    *time.Time      `json:"executedAt,omitempty"`
+	Latency       *uint64         `json:"latency,omitempty"` // in seconds
+	Relayer       *common.Address `json:"relayer,omitempty"` // sender of the execution transaction
+	ExecutionTx   *common.Hash    `json:"executionTx,omitempty"`
+	Failures      uint64          `json:"failures,omitempty"`    // reverted execution attempts
+	LastFailure   string          `json:"lastFailure,omitempty"` // reason of the last one
+	Payload       *Payload        `json:"payload,omitempty"`     // decoded message, if a decoder knows the call
+
+	sloDone        []bool // whether the message already counted towards each SLO
+	relayerCounted bool   // whether the latency already counted towards its relayer
+}
+
+// Keeps the records of pending messages, and a bounded list of the finished ones
+type MessageStore struct {
+	pending  map[Identifier]*MessageRecord
+	finished []*MessageRecord              // oldest first
+	records  map[Identifier]*MessageRecord // both pending and finished, by id
+	size     int
+}
+
+func NewMessageStore(size int) *MessageStore {
+	return &MessageStore{
+		pending: make(map[Identifier]*MessageRecord),
+		records: make(map[Identifier]*MessageRecord),
+		size:    size,
+	}
+}
+
+func (s *MessageStore) get(id Identifier) *MessageRecord {
+	return s.records[id]
+}
+
+func (s *MessageStore) sent(id Identifier) *MessageRecord {
+	record := &MessageRecord{
+		Id:        id,
+		Status:    MessagePending,
+		SentBlock: id.BlockNumber,
+		SentAt:    time.Unix(int64(id.Timestamp), 0).UTC(),
+	}
+	s.pending[id] = record
+	s.records[id] = record
+
+	return record
+}
+
+// Also accepts expired and reconciled messages, whose relay log arrived late. Returns the previous status
+func (s *MessageStore) relayed(id Identifier, executedBlock, sentTimestamp, executedTimestamp uint64) (record *MessageRecord, previous MessageStatus) {
+	record, ok := s.pending[id]
+	if !ok {
+		if record = s.records[id]; record == nil || (record.Status != MessageReconciled && record.Status != MessageExpired) {
+			return nil, ""
+		}
+	}
+	previous = record.Status
+
+	executedAt := time.Unix(int64(executedTimestamp), 0).UTC()
+	latency := executedTimestamp - min(sentTimestamp, executedTimestamp)
+
+	record.Status = MessageRelayed
+	record.ExecutedBlock = executedBlock
+	record.ExecutedAt = &executedAt
+	record.Latency = &latency
+	if previous == MessagePending {
+		s.finish(record)
+	}
+
+	return record, previous
+}
+
+func (s *MessageStore) expired(id Identifier) *MessageRecord {
+	record, ok := s.pending[id]
+	if !ok {
+		return nil
+	}
+
+	record.Status = MessageExpired
+	s.finish(record)
+
+	return record
+}
+
+// Marks a pending or expired message as relayed on-chain. Returns the previous status
+func (s *MessageStore) reconciled(id Identifier) (record *MessageRecord, previous MessageStatus) {
+	record = s.records[id]
+	if record == nil || (record.Status != MessagePending && record.Status != MessageExpired) {
+		return nil, ""
+	}
+
+	previous = record.Status
+	record.Status = MessageReconciled
+	if previous == MessagePending {
+		s.finish(record)
+	}
+
+	return record, previous
+}
+
+func (s *MessageStore) finish(record *MessageRecord) {
+	delete(s.pending, record.Id)
+
+	s.finished = append(s.finished, record)
+	if len(s.finished) > s.size {
+		for _, old := range s.finished[:len(s.finished)-s.size] {
+			delete(s.records, old.Id)
+		}
+		s.finished = s.finished[len(s.finished)-s.size:]
+	}
+}
+
+// Filters for querying message records, zero values match everything
+type MessageQuery struct {
+	Status MessageStatus
+	MinAge time.Duration // only pending messages sent at least this long ago
+	Limit  int
+}
+
+// Returns the pending messages oldest first, followed by the finished ones newest first
+func (agg *Aggregator) Messages(q MessageQuery) []MessageRecord {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	records := make([]MessageRecord, 0)
+	full := func() bool { return q.Limit > 0 && len(records) >= q.Limit }
+
+	if q.Status == "" || q.Status == MessagePending {
+		pending := make([]*MessageRecord, 0, len(agg.messages.pending))
+		for _, record := range agg.messages.pending {
+			if q.MinAge == 0 || time.Since(record.SentAt) >= q.MinAge {
+				pending = append(pending, record)
+			}
+		}
+
+		sort.Slice(pending, func(i, j int) bool {
+			return pending[i].SentAt.Before(pending[j].SentAt)
+		})
+
+		for _, record := range pending {
+			if full() {
+				return records
+			}
+			records = append(records, *record)
+		}
+	}
+
+	// stuck messages are pending by definition
+	if q.Status == MessagePending || q.MinAge != 0 {
+		return records
+	}
+
+	for i := len(agg.messages.finished) - 1; i >= 0 && !full(); i-- {
+		record := agg.messages.finished[i]
+		if q.Status == "" || q.Status == record.Status {
+			records = append(records, *record)
+		}
+	}
+
+	return records
+}
+
+// Latency distribution of the messages relayed during a time bucket
+type LatencyBucket struct {
+	Time  time.Time `json:"time"` // start of the bucket
+	Count int       `json:"count"`
+	P50   uint64    `json:"p50"`
+	P90   uint64    `json:"p90"`
+	P99   uint64    `json:"p99"`
+	Max   uint64    `json:"max"`
+}
+
+// Buckets the relayed messages of the history by execution time, oldest first
+func (agg *Aggregator) LatencyPercentiles(bucket time.Duration, since time.Time) []LatencyBucket {
+	latencies := make(map[int64][]uint64)
+
+	agg.mu.RLock()
+	for _, record := range agg.messages.finished {
+		if record.Status != MessageRelayed || record.ExecutedAt.Before(since) {
+			continue
+		}
+
+		start := record.ExecutedAt.Truncate(bucket).Unix()
+		latencies[start] = append(latencies[start], *record.Latency)
+	}
+	agg.mu.RUnlock()
+
+	buckets := make([]LatencyBucket, 0, len(latencies))
+	for start, values := range latencies {
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+		// nearest rank percentile
+		percentile := func(p float64) uint64 {
+			rank := int(math.Ceil(p*float64(len(values)))) - 1
+			return values[max(rank, 0)]
+		}
+
+		buckets = append(buckets, LatencyBucket{
+			Time:  time.Unix(start, 0).UTC(),
+			Count: len(values),
+			P50:   percentile(0.5),
+			P90:   percentile(0.9),
+			P99:   percentile(0.99),
+			Max:   values[len(values)-1],
+		})
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Time.Before(buckets[j].Time)
+	})
+
+	return buckets
+}
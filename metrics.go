@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Writes metrics in the Prometheus text exposition format. Samples are grouped by family,
+// in the order families are first written, since the format requires them to be contiguous
+type metricsWriter struct {
+	families []*metricFamily
+	byName   map[string]*metricFamily
+}
+
+type metricFamily struct {
+	name, kind, help string
+	samples          strings.Builder
+}
+
+// labels are name, value pairs
+func (w *metricsWriter) write(name, kind, help string, value float64, labels ...string) {
+	if w.byName == nil {
+		w.byName = make(map[string]*metricFamily)
+	}
+
+	family, ok := w.byName[name]
+	if !ok {
+		family = &metricFamily{name: name, kind: kind, help: help}
+		w.byName[name] = family
+		w.families = append(w.families, family)
+	}
+
+	b := &family.samples
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=%s", labels[i], strconv.Quote(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(b, " %s\n", strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (w *metricsWriter) String() string {
+	var b strings.Builder
+	for _, family := range w.families {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+		b.WriteString(family.samples.String())
+	}
+	return b.String()
+}
+
+func (agg *Aggregator) writeMetrics(w *metricsWriter) {
+	pair := agg.Name()
+
+	agg.mu.RLock()
+	latest := *agg.LatestBlock
+	pending := len(agg.messages.pending)
+	costs := agg.costs.total
+	checked, discrepancies := agg.reconciler.checked, agg.reconciler.total
+	replays := agg.replays.replayed
+	failures := make(map[string]uint64, len(agg.failures.byReason))
+	for reason, n := range agg.failures.byReason {
+		failures[reason] = n
+	}
+	agg.mu.RUnlock()
+
+	agg.nonces.mu.Lock()
+	started, lastNonce, missingNonces := agg.nonces.started, agg.nonces.last, agg.nonces.missing
+	agg.nonces.mu.Unlock()
+
+	w.write("interop_latest_block", "gauge", "Latest sender block with a message.", float64(latest), "pair", pair)
+	w.write("interop_pending_messages", "gauge", "Messages sent and not relayed yet.", float64(pending), "pair", pair)
+
+	w.write("interop_relay_transactions_total", "counter", "Relay transactions with a fetched receipt.", float64(costs.Transactions), "pair", pair)
+	w.write("interop_relay_gas_used_total", "counter", "Gas used by the relay transactions.", float64(costs.GasUsed), "pair", pair)
+	w.write("interop_relay_l1_fee_wei_total", "counter", "L1 data fees paid by the relay transactions, in wei.", weiFloat(costs.L1Fee), "pair", pair)
+	w.write("interop_relay_cost_wei_total", "counter", "Total cost of the relay transactions, in wei, including the L1 fee.", weiFloat(costs.Cost), "pair", pair)
+
+	w.write("interop_reconcile_checks_total", "counter", "Messages checked with successfulMessages.", float64(checked), "pair", pair)
+	w.write("interop_reconcile_discrepancies_total", "counter", "Messages relayed on-chain while the monitor had them pending or expired.", float64(discrepancies), "pair", pair)
+
+	if started {
+		w.write("interop_sent_nonce", "gauge", "Highest nonce seen in the SentMessage logs of the sender messenger.", float64(lastNonce), "pair", pair)
+	}
+	w.write("interop_missing_nonces", "gauge", "Nonces skipped by the SentMessage logs and not seen since.", float64(missingNonces), "pair", pair)
+	w.write("interop_relay_replays_total", "counter", "Message hashes relayed again by another transaction.", float64(replays), "pair", pair)
+
+	reasons := make([]string, 0, len(failures))
+	for reason := range failures {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	for _, reason := range reasons {
+		w.write("interop_execution_failures_total", "counter", "Reverted execution transactions by reason.", float64(failures[reason]), "pair", pair, "reason", reason)
+	}
+
+	report := agg.Relayers(0)
+	relayers := append(report.Items, report.Other)
+
+	for _, r := range relayers {
+		labels := []string{"pair", pair, "relayer", r.Address}
+		w.write("interop_relayer_messages_total", "counter", "Messages executed by the relayer.", float64(r.Messages), labels...)
+		w.write("interop_relayer_transactions_total", "counter", "Successful execution transactions sent by the relayer.", float64(r.Transactions), labels...)
+		w.write("interop_relayer_failed_transactions_total", "counter", "Reverted execution transactions sent by the relayer.", float64(r.Failed), labels...)
+		w.write("interop_relayer_gas_used_total", "counter", "Gas used by the execution transactions of the relayer.", float64(r.GasUsed), labels...)
+		w.write("interop_relayer_cost_wei_total", "counter", "Total cost of the execution transactions of the relayer, in wei.", weiFloat(r.Cost), labels...)
+		w.write("interop_relayer_latency_seconds_sum", "counter", "Sum of the latencies of the messages executed by the relayer.", float64(r.TotalLatency), labels...)
+		w.write("interop_relayer_latency_seconds_count", "counter", "Messages executed by the relayer with a known latency.", float64(r.Latencies), labels...)
+	}
+
+	inflight := agg.Inflight()
+	w.write("interop_inflight_undecoded_messages", "gauge", "Pending or expired messages without a decoded token and amount.", float64(inflight.Undecoded), "pair", pair)
+
+	for _, t := range inflight.Tokens {
+		labels := []string{"pair", pair, "token", t.Token.Hex()}
+		w.write("interop_inflight_messages", "gauge", "Pending or expired messages moving the token.", float64(t.Messages), labels...)
+		w.write("interop_inflight_amount", "gauge", "Decoded amount of the token in pending or expired messages, in its smallest unit.", weiFloat(t.Amount), labels...)
+		w.write("interop_inflight_expired_messages", "gauge", "Expired messages moving the token.", float64(t.ExpiredMessages), labels...)
+		w.write("interop_inflight_expired_amount", "gauge", "Decoded amount of the token in expired messages, in its smallest unit.", weiFloat(t.Expired), labels...)
+	}
+
+	if agg.config.TrackInboxOrigins {
+		origins := agg.Origins(0)
+		for _, o := range append(origins.Items, origins.Other) {
+			labels := []string{"pair", pair, "origin", o.Origin}
+			w.write("interop_origin_executions_total", "counter", "Executing messages of the CrossL2Inbox referencing a log of the origin contract.", float64(o.Executions), labels...)
+			w.write("interop_origin_invalid_total", "counter", "Executing messages whose initiating log doesn't exist or doesn't match.", float64(o.Invalid), labels...)
+			w.write("interop_origin_unresolved_total", "counter", "Executing messages whose initiating log couldn't be fetched.", float64(o.Unresolved), labels...)
+			w.write("interop_origin_latency_seconds_sum", "counter", "Sum of the latencies between the initiating logs and their execution.", float64(o.TotalLatency), labels...)
+			w.write("interop_origin_latency_seconds_count", "counter", "Executing messages with a resolved initiating log.", float64(o.Resolved), labels...)
+		}
+	}
+
+	if agg.supervisorClient != nil {
+		supervisor := agg.Supervisor(0)
+
+		levels := make([]string, 0, len(supervisor.Levels))
+		for level := range supervisor.Levels {
+			levels = append(levels, level)
+		}
+		sort.Strings(levels)
+
+		for _, level := range levels {
+			w.write("interop_supervisor_checks_total", "counter", "Executing messages checked with the supervisor, by safety level.", float64(supervisor.Levels[level]), "pair", pair, "safety", level)
+		}
+		w.write("interop_supervisor_disagreements_total", "counter", "Executing messages the supervisor considers invalid while the monitor paired them.", float64(supervisor.Total), "pair", pair)
+
+		for _, chain := range supervisor.Chains {
+			labels := []string{"pair", pair, "chain", strconv.FormatUint(chain.ChainId, 10)}
+			w.write("interop_supervisor_cross_unsafe_block", "gauge", "Cross-unsafe head of the chain according to the supervisor.", float64(chain.CrossUnsafe), labels...)
+			w.write("interop_supervisor_cross_safe_block", "gauge", "Cross-safe head of the chain according to the supervisor.", float64(chain.CrossSafe), labels...)
+			w.write("interop_supervisor_cross_safe_lag_blocks", "gauge", "Blocks between the cross-unsafe and cross-safe heads of the chain.", float64(chain.CrossSafeLag), labels...)
+		}
+	}
+}
+
+func writeAlertMetrics(w *metricsWriter) {
+	firing := map[string]int{SeverityWarning: 0, SeverityCritical: 0}
+	for _, alert := range alertManager.Active() {
+		if !alert.Silenced {
+			firing[alert.Severity]++
+		}
+	}
+
+	severities := make([]string, 0, len(firing))
+	for severity := range firing {
+		severities = append(severities, severity)
+	}
+	sort.Strings(severities)
+
+	for _, severity := range severities {
+		w.write("interop_alerts_firing", "gauge", "Firing alerts that are not silenced.", float64(firing[severity]), "severity", severity)
+	}
+
+	w.write("interop_alert_deliveries_pending", "gauge", "Alert deliveries waiting to be sent.", float64(len(alertOutbox.Deliveries(DeliveryPending))))
+}
+
+func metricsRoute(pairs func() []*Aggregator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		w := &metricsWriter{}
+
+		for _, agg := range pairs() {
+			agg.writeMetrics(w)
+		}
+		writeAlertMetrics(w)
+
+		return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(w.String()))
+	}
+}
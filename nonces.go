@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Nonces the sender messenger skipped, i.e. SentMessage logs the monitor missed
+type NonceGap struct {
+	From       uint64    `json:"from"`  // first missing nonce
+	To         uint64    `json:"to"`    // last missing nonce
+	Block      uint64    `json:"block"` // sender block of the message after the gap
+	DetectedAt time.Time `json:"detectedAt"`
+	Filled     uint64    `json:"filled"` // missing nonces seen later, e.g. out of order logs
+
+	seen map[uint64]bool // filled nonces, so duplicate logs count once
+}
+
+func (g *NonceGap) size() uint64 {
+	return g.To - g.From + 1
+}
+
+func (g *NonceGap) String() string {
+	if g.From == g.To {
+		return fmt.Sprintf("%d", g.From)
+	}
+	return fmt.Sprintf("%d-%d", g.From, g.To)
+}
+
+// A RelayedMessage seen again for a message hash already relayed by another transaction.
+// The messenger rejects replays, so this points at a messenger or log ingestion issue
+type Replay struct {
+	MessageHash common.Hash `json:"messageHash"`
+	Nonce       *big.Int    `json:"nonce"`
+	FirstTx     common.Hash `json:"firstTx"`
+	FirstBlock  uint64      `json:"firstBlock"` // receiver block
+	TxHash      common.Hash `json:"txHash"`
+	Block       uint64      `json:"block"` // receiver block
+	DetectedAt  time.Time   `json:"detectedAt"`
+}
+
+type relayLog struct {
+	txHash common.Hash
+	block  uint64
+}
+
+type nonceKey struct {
+	chainId   uint64
+	messenger common.Address
+}
+
+// Follows the nonce sequence of a sender messenger, which numbers the messages of every
+// destination. The pairs of the sender share the tracker and feed it all the SentMessage logs,
+// before keeping the ones of their receiver, so each nonce, and each gap, counts once
+type NonceTracker struct {
+	mu      sync.Mutex
+	key     nonceKey
+	pairs   map[string]struct{} // guarded by nonceTrackers.mu
+	started bool
+	first   uint64
+	last    uint64      // highest nonce seen
+	sent    uint64      // SentMessage logs with a new nonce
+	gaps    []*NonceGap // oldest first
+	missing uint64      // nonces of the gaps not filled yet
+	gapped  []*NonceGap // gaps since the last alert check
+
+	size int
+}
+
+// Trackers by sender chain id and messenger
+var nonceTrackers = struct {
+	mu       sync.Mutex
+	bySender map[nonceKey]*NonceTracker
+}{bySender: make(map[nonceKey]*NonceTracker)}
+
+// Returns the tracker of the sender messenger, shared with the other pairs of the sender
+func acquireNonceTracker(chainId uint64, messenger common.Address, pair string, size int) *NonceTracker {
+	key := nonceKey{chainId: chainId, messenger: messenger}
+
+	nonceTrackers.mu.Lock()
+	defer nonceTrackers.mu.Unlock()
+
+	t, ok := nonceTrackers.bySender[key]
+	if !ok {
+		t = &NonceTracker{key: key, pairs: make(map[string]struct{}), size: size}
+		nonceTrackers.bySender[key] = t
+	}
+	t.pairs[pair] = struct{}{}
+
+	return t
+}
+
+// Removes the pair from the tracker, which is dropped with its last pair
+func (t *NonceTracker) release(pair string) {
+	nonceTrackers.mu.Lock()
+	defer nonceTrackers.mu.Unlock()
+
+	delete(t.pairs, pair)
+	if len(t.pairs) == 0 && nonceTrackers.bySender[t.key] == t {
+		delete(nonceTrackers.bySender, t.key)
+	}
+}
+
+// Pair alerting on the gaps of the sender, the first by name
+func (t *NonceTracker) owner() string {
+	nonceTrackers.mu.Lock()
+	defer nonceTrackers.mu.Unlock()
+
+	var owner string
+	for pair := range t.pairs {
+		if owner == "" || pair < owner {
+			owner = pair
+		}
+	}
+	return owner
+}
+
+// Returns the gap ending right before the nonce, if any. Nonces above 64 bits are ignored
+func (t *NonceTracker) observeSent(nonce *big.Int, block uint64) *NonceGap {
+	if nonce == nil || !nonce.IsUint64() {
+		return nil
+	}
+	n := nonce.Uint64()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.started {
+		t.started = true
+		t.first, t.last = n, n
+		t.sent++
+		return nil
+	}
+
+	if n <= t.last {
+		if t.fill(n) {
+			t.sent++
+		}
+		return nil
+	}
+
+	var gap *NonceGap
+	if n > t.last+1 {
+		gap = &NonceGap{
+			From:       t.last + 1,
+			To:         n - 1,
+			Block:      block,
+			DetectedAt: time.Now().UTC(),
+			seen:       make(map[uint64]bool),
+		}
+		t.missing += gap.size()
+		t.gapped = append(t.gapped, gap)
+
+		t.gaps = append(t.gaps, gap)
+		if len(t.gaps) > t.size {
+			for _, old := range t.gaps[:len(t.gaps)-t.size] {
+				t.missing -= old.size() - old.Filled
+			}
+			t.gaps = t.gaps[len(t.gaps)-t.size:]
+		}
+	}
+
+	t.last = n
+	t.sent++
+
+	return gap
+}
+
+// Counts a late nonce in its gap, returns false for nonces already seen
+func (t *NonceTracker) fill(n uint64) bool {
+	for _, gap := range t.gaps {
+		if n < gap.From || n > gap.To || gap.seen[n] {
+			continue
+		}
+		gap.seen[n] = true
+		gap.Filled++
+		t.missing--
+		return true
+	}
+
+	return false
+}
+
+// Follows the message hashes relayed on the receiver from the sender of the pair
+type ReplayTracker struct {
+	relayed  map[common.Hash]relayLog
+	order    []common.Hash // relayed hashes oldest first, to bound the map
+	replays  []Replay      // oldest first
+	replayed uint64        // since the monitor started
+	window   uint64        // replays since the last alert check
+
+	size int
+}
+
+func NewReplayTracker(size int) *ReplayTracker {
+	return &ReplayTracker{
+		relayed: make(map[common.Hash]relayLog),
+		size:    size,
+	}
+}
+
+// Returns the replay when the hash was already relayed by another transaction. The same
+// log ingested twice, e.g. after a reorg, has the same transaction and isn't a replay
+func (t *ReplayTracker) observeRelayed(hash common.Hash, nonce *big.Int, txHash common.Hash, block uint64) *Replay {
+	first, ok := t.relayed[hash]
+	if !ok {
+		t.relayed[hash] = relayLog{txHash: txHash, block: block}
+		t.order = append(t.order, hash)
+		if len(t.order) > t.size {
+			for _, old := range t.order[:len(t.order)-t.size] {
+				delete(t.relayed, old)
+			}
+			t.order = t.order[len(t.order)-t.size:]
+		}
+		return nil
+	}
+
+	if first.txHash == txHash {
+		return nil
+	}
+
+	replay := Replay{
+		MessageHash: hash,
+		Nonce:       nonce,
+		FirstTx:     first.txHash,
+		FirstBlock:  first.block,
+		TxHash:      txHash,
+		Block:       block,
+		DetectedAt:  time.Now().UTC(),
+	}
+	t.replayed++
+	t.window++
+
+	t.replays = append(t.replays, replay)
+	if len(t.replays) > t.size {
+		t.replays = t.replays[len(t.replays)-t.size:]
+	}
+
+	return &replay
+}
+
+// Fires when nonces went missing since the last check
+func (t *NonceTracker) checkGaps(pair string) AlertCheck {
+	t.mu.Lock()
+	gapped := t.gapped
+	t.gapped = nil
+	t.mu.Unlock()
+
+	var missing uint64
+	var ranges []string
+	for _, gap := range gapped {
+		missing += gap.size()
+		ranges = append(ranges, gap.String())
+	}
+
+	value := fmt.Sprintf("%d", missing)
+	if len(ranges) > 0 {
+		value += " (nonces " + strings.Join(ranges, ", ") + ")"
+	}
+
+	return AlertCheck{
+		Rule:      "nonceGap",
+		Type:      "Nonce Gap",
+		Severity:  SeverityWarning,
+		Pair:      pair,
+		Firing:    missing > 0,
+		Value:     value,
+		Threshold: "0",
+	}
+}
+
+// Fires when a message was relayed twice since the last check
+func (t *ReplayTracker) checkReplays(pair string) AlertCheck {
+	window := t.window
+	t.window = 0
+
+	return AlertCheck{
+		Rule:      "relayReplay",
+		Type:      "Relay Replay",
+		Severity:  SeverityCritical,
+		Pair:      pair,
+		Firing:    window > 0,
+		Value:     fmt.Sprintf("%d", window),
+		Threshold: "0",
+	}
+}
+
+type NonceReport struct {
+	Pair      string     `json:"pair"`
+	Messenger string     `json:"messenger"` // sender messenger
+	First     *uint64    `json:"first"`     // first nonce seen, null until a message is sent
+	Last      *uint64    `json:"last"`      // highest nonce seen
+	Sent      uint64     `json:"sent"`      // distinct nonces seen
+	Missing   uint64     `json:"missing"`   // nonces of the listed gaps not seen yet
+	Gaps      []NonceGap `json:"gaps"`      // newest first
+	Replayed  uint64     `json:"replayed"`  // since the monitor started
+	Replays   []Replay   `json:"replays"`   // newest first
+}
+
+// Returns the nonce sequence of the sender messenger, with the recent gaps and replays newest first
+func (agg *Aggregator) Nonces(limit int) NonceReport {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	report := NonceReport{
+		Pair:      agg.Name(),
+		Messenger: agg.messengerContract.Address.Hex(),
+		Gaps:      make([]NonceGap, 0),
+		Replayed:  agg.replays.replayed,
+		Replays:   make([]Replay, 0),
+	}
+
+	t := agg.nonces
+	t.mu.Lock()
+	report.Sent, report.Missing = t.sent, t.missing
+	if t.started {
+		first, last := t.first, t.last
+		report.First, report.Last = &first, &last
+	}
+	for i := len(t.gaps) - 1; i >= 0 && (limit == 0 || len(report.Gaps) < limit); i-- {
+		report.Gaps = append(report.Gaps, *t.gaps[i])
+	}
+	t.mu.Unlock()
+
+	replays := agg.replays.replays
+	for i := len(replays) - 1; i >= 0 && (limit == 0 || len(report.Replays) < limit); i-- {
+		report.Replays = append(report.Replays, replays[i])
+	}
+
+	return report
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OpenAPI 3 description of the API, must be updated along with the routes
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func openAPIRoute(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, openAPISpec)
+}
+
+var echoPathParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// Logs the registered routes missing from the OpenAPI document, so they don't drift apart
+func checkOpenAPIRoutes(e *echo.Echo) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		log.Printf("invalid OpenAPI document: %v", err)
+		return
+	}
+
+	for _, route := range e.Routes() {
+		// static files, only their root is documented
+		if strings.HasSuffix(route.Path, "*") {
+			continue
+		}
+
+		path := echoPathParam.ReplaceAllString(route.Path, "{$1}")
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			log.Printf("route %s %s is not documented in openapi.json", route.Method, route.Path)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	originQueueSize   = 4096
+	originMaxAttempts = 3
+
+	OriginResolved   = "resolved"   // the initiating log exists, with the executed payload
+	OriginInvalid    = "invalid"    // no such log, or a different payload or timestamp
+	OriginUnresolved = "unresolved" // the sender chain couldn't be queried
+)
+
+// Executing messages of a single origin contract, since the monitor started
+type OriginStat struct {
+	Origin       string    `json:"origin"` // contract that emitted the initiating logs, or "other"
+	Executions   uint64    `json:"executions"`
+	Resolved     uint64    `json:"resolved"`
+	Invalid      uint64    `json:"invalid"`
+	Unresolved   uint64    `json:"unresolved"`
+	TotalLatency uint64    `json:"totalLatency"` // in seconds, over the resolved executions
+	AvgLatency   float64   `json:"avgLatency"`
+	MaxLatency   uint64    `json:"maxLatency"`
+	LastSeen     time.Time `json:"lastSeen"` // last execution
+}
+
+func (s *OriginStat) add(o *OriginStat) {
+	s.Executions += o.Executions
+	s.Resolved += o.Resolved
+	s.Invalid += o.Invalid
+	s.Unresolved += o.Unresolved
+	s.TotalLatency += o.TotalLatency
+	s.MaxLatency = max(s.MaxLatency, o.MaxLatency)
+	if o.LastSeen.After(s.LastSeen) {
+		s.LastSeen = o.LastSeen
+	}
+}
+
+func (s *OriginStat) key() string { return s.Origin }
+
+func (s *OriginStat) activity() (uint64, time.Time) { return s.Executions, s.LastSeen }
+
+func (s *OriginStat) finalize() {
+	if s.Resolved > 0 {
+		s.AvgLatency = float64(s.TotalLatency) / float64(s.Resolved)
+	}
+}
+
+// An ExecutingMessage of the CrossL2Inbox, and the initiating log it references
+type OriginExecution struct {
+	Id         Identifier  `json:"id"` // identifier of the initiating log
+	MsgHash    common.Hash `json:"msgHash"`
+	TxHash     common.Hash `json:"txHash"`
+	Block      uint64      `json:"block"` // receiver block
+	ExecutedAt time.Time   `json:"executedAt"`
+	Status     string      `json:"status"`
+	Reason     string      `json:"reason,omitempty"`  // why the execution is invalid or unresolved
+	Latency    *uint64     `json:"latency,omitempty"` // in seconds, once resolved
+}
+
+// Stats keyed by origin contract, bounded to the `size` busiest ones like the breakdowns,
+// and the last `history` invalid executions
+type OriginTracker struct {
+	boundedStats[OriginStat, *OriginStat]
+	invalid []OriginExecution // oldest first
+	history int
+	jobs    chan OriginExecution
+}
+
+func NewOriginTracker(size, history int) *OriginTracker {
+	return &OriginTracker{
+		boundedStats: newBoundedStats[OriginStat, *OriginStat](size, OriginStat{Origin: "other"}, func(key common.Address) OriginStat {
+			return OriginStat{Origin: key.Hex()}
+		}),
+		history: history,
+		jobs:    make(chan OriginExecution, originQueueSize),
+	}
+}
+
+// Counts the execution and queues the resolution of its initiating log
+func (t *OriginTracker) executed(exec OriginExecution) {
+	stat := t.get(exec.Id.Origin, true)
+	stat.Executions++
+	stat.LastSeen = exec.ExecutedAt
+
+	select {
+	case t.jobs <- exec:
+	default:
+		exec.Status, exec.Reason = OriginUnresolved, "queue full"
+		t.resolved(exec)
+	}
+}
+
+func (t *OriginTracker) resolved(exec OriginExecution) {
+	stat := t.get(exec.Id.Origin, false)
+
+	switch exec.Status {
+	case OriginResolved:
+		stat.Resolved++
+		stat.TotalLatency += *exec.Latency
+		stat.MaxLatency = max(stat.MaxLatency, *exec.Latency)
+	case OriginInvalid:
+		stat.Invalid++
+		t.invalid = append(t.invalid, exec)
+		if len(t.invalid) > t.history {
+			t.invalid = t.invalid[len(t.invalid)-t.history:]
+		}
+	default:
+		stat.Unresolved++
+	}
+}
+
+// Returns the `limit` origins with the most executions, and the sum of the rest
+func (t *OriginTracker) Top(limit int) (items []OriginStat, other OriginStat) {
+	items, other = t.top(limit)
+	for i := range items {
+		items[i].finalize()
+	}
+	other.finalize()
+
+	return
+}
+
+// Hash the CrossL2Inbox expects for a log: its topics and data concatenated
+func logPayloadHash(l *types.Log) common.Hash {
+	var payload []byte
+	for _, topic := range l.Topics {
+		payload = append(payload, topic.Bytes()...)
+	}
+	payload = append(payload, l.Data...)
+
+	return crypto.Keccak256Hash(payload)
+}
+
+// Resolves the initiating logs of the queued executions on the sender chain
+func (agg *Aggregator) WatchOrigins() {
+	for {
+		var exec OriginExecution
+		select {
+		case exec = <-agg.origins.jobs:
+		case <-agg.done:
+			return
+		}
+
+		var err error
+		for attempt := 0; attempt < originMaxAttempts; attempt++ {
+			if attempt > 0 && !agg.sleep(time.Second<<(attempt-1)) {
+				return
+			}
+			if err = agg.resolveOrigin(&exec); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Printf("origins: failed to resolve %s: %v", exec.TxHash.Hex(), err)
+			exec.Status, exec.Reason = OriginUnresolved, err.Error()
+		}
+
+		agg.mu.Lock()
+		agg.origins.resolved(exec)
+		agg.mu.Unlock()
+	}
+}
+
+// Sets the status of the execution, returns an error when the sender chain couldn't be queried
+func (agg *Aggregator) resolveOrigin(exec *OriginExecution) error {
+	number := new(big.Int).SetUint64(exec.Id.BlockNumber)
+
+	logs, err := agg.Sender.FetchLogs(exec.Id.Origin, number, number)
+	if err != nil {
+		return err
+	}
+
+	var initiating *types.Log
+	for i := range logs {
+		if uint64(logs[i].Index) == exec.Id.LogIndex {
+			initiating = &logs[i]
+			break
+		}
+	}
+	if initiating == nil {
+		exec.Status, exec.Reason = OriginInvalid, "log not found"
+		return nil
+	}
+	if hash := logPayloadHash(initiating); hash != exec.MsgHash {
+		exec.Status, exec.Reason = OriginInvalid, fmt.Sprintf("payload hash is %s", hash.Hex())
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	header, err := agg.Sender.Client.HeaderByNumber(ctx, number)
+	if err != nil {
+		return err
+	}
+	if header.Time != exec.Id.Timestamp {
+		exec.Status, exec.Reason = OriginInvalid, fmt.Sprintf("block timestamp is %d", header.Time)
+		return nil
+	}
+
+	executedAt := uint64(exec.ExecutedAt.Unix())
+	latency := executedAt - min(header.Time, executedAt)
+	exec.Status, exec.Latency = OriginResolved, &latency
+
+	return nil
+}
+
+type OriginReport struct {
+	Pair    string            `json:"pair"`
+	Items   []OriginStat      `json:"items"`
+	Other   OriginStat        `json:"other"`   // sum of the origins not listed
+	Invalid []OriginExecution `json:"invalid"` // newest first
+}
+
+// Returns the `limit` origin contracts with the most executions, and the recent invalid executions newest first
+func (agg *Aggregator) Origins(limit int) OriginReport {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	items, other := agg.origins.Top(limit)
+	report := OriginReport{Pair: agg.Name(), Items: items, Other: other, Invalid: make([]OriginExecution, 0)}
+
+	invalid := agg.origins.invalid
+	for i := len(invalid) - 1; i >= 0 && (limit == 0 || len(report.Invalid) < limit); i-- {
+		report.Invalid = append(report.Invalid, invalid[i])
+	}
+
+	return report
+}
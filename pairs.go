@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type monitoredPair struct {
+	agg        *Aggregator
+	discovered bool // from the dependency set, removed once it leaves it
+	addedAt    time.Time
+}
+
+// The pairs being monitored, the configured one and the ones discovered from the dependency set
+type Monitor struct {
+	config     *Config
+	supervisor Supervisor // nil unless a supervisor is configured
+	errChan    chan error
+
+	mu     sync.RWMutex
+	pairs  map[string]*monitoredPair
+	chains map[uint64]*Chain // connected chains of `rpcs`, shared by their pairs
+}
+
+func NewMonitor(config *Config, supervisor Supervisor, errChan chan error) *Monitor {
+	return &Monitor{
+		config:     config,
+		supervisor: supervisor,
+		errChan:    errChan,
+		pairs:      make(map[string]*monitoredPair),
+		chains:     make(map[uint64]*Chain),
+	}
+}
+
+// Connects to the RPC of the chain in `rpcs`, once
+func (m *Monitor) chain(id uint64) (*Chain, error) {
+	m.mu.RLock()
+	chain, ok := m.chains[id]
+	m.mu.RUnlock()
+	if ok {
+		return chain, nil
+	}
+
+	chain, err := NewChain(m.config.RPCs[id])
+	if err != nil {
+		return nil, err
+	}
+	if chain.ChainId.Uint64() != id {
+		return nil, fmt.Errorf("rpc of chain %d is for chain %s", id, chain.ChainId)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.chains[id]; ok {
+		chain.Client.Close()
+		return existing, nil
+	}
+	m.chains[id] = chain
+
+	return chain, nil
+}
+
+// Starts monitoring the pair
+func (m *Monitor) Add(cp ContractPair, discovered bool) error {
+	agg, err := cp.FetchAggregateCycle(m.config, m.errChan)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.pairs[agg.Name()] = &monitoredPair{agg: &agg, discovered: discovered, addedAt: time.Now().UTC()}
+	m.mu.Unlock()
+
+	log.Printf("pairs: monitoring %s", agg.Name())
+	return nil
+}
+
+// Stops monitoring the pair and resolves its alerts
+func (m *Monitor) Remove(name string) {
+	m.mu.Lock()
+	pair, ok := m.pairs[name]
+	delete(m.pairs, name)
+	m.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	pair.agg.Stop()
+	alertManager.ResolvePair(name)
+
+	log.Printf("pairs: stopped monitoring %s", name)
+}
+
+// Returns the monitored pairs by name
+func (m *Monitor) Pairs() []*Aggregator {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	aggs := make([]*Aggregator, 0, len(m.pairs))
+	for _, pair := range m.pairs {
+		aggs = append(aggs, pair.agg)
+	}
+	sort.Slice(aggs, func(i, j int) bool { return aggs[i].Name() < aggs[j].Name() })
+
+	return aggs
+}
+
+func (m *Monitor) discovered() []*Aggregator {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var aggs []*Aggregator
+	for _, pair := range m.pairs {
+		if pair.discovered {
+			aggs = append(aggs, pair.agg)
+		}
+	}
+
+	return aggs
+}
+
+// Returns the named pair, or the only one when the name is empty
+func (m *Monitor) Pair(name string) (*Aggregator, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if name == "" {
+		if len(m.pairs) != 1 {
+			return nil, errors.New("`pair` is required unless monitoring a single pair")
+		}
+		for _, pair := range m.pairs {
+			return pair.agg, nil
+		}
+	}
+
+	pair, ok := m.pairs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown pair %q", name)
+	}
+
+	return pair.agg, nil
+}
+
+// Serves the route for the pair of the `pair` query param
+func (m *Monitor) route(h func(*Aggregator, echo.Context) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		agg, err := m.Pair(c.QueryParam("pair"))
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		return h(agg, c)
+	}
+}
+
+type PairInfo struct {
+	Pair       string    `json:"pair"`
+	Sender     string    `json:"sender"`   // chain id
+	Receiver   string    `json:"receiver"` // chain id
+	Discovered bool      `json:"discovered"`
+	AddedAt    time.Time `json:"addedAt"`
+}
+
+func (m *Monitor) PairsRoute(c echo.Context) error {
+	m.mu.RLock()
+	pairs := make([]PairInfo, 0, len(m.pairs))
+	for name, pair := range m.pairs {
+		pairs = append(pairs, PairInfo{
+			Pair:       name,
+			Sender:     pair.agg.Sender.ChainId.String(),
+			Receiver:   pair.agg.Receiver.ChainId.String(),
+			Discovered: pair.discovered,
+			AddedAt:    pair.addedAt,
+		})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Pair < pairs[j].Pair })
+
+	return c.JSON(http.StatusOK, pairs)
+}
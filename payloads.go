@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Token of the ETH bridge payloads
+var EtherToken = common.Address{}
+
+var (
+	superchainTokenBridge = common.HexToAddress("0x4200000000000000000000000000000000000028")
+	superchainETHBridge   = common.HexToAddress("0x4200000000000000000000000000000000000024")
+)
+
+// Decoders of the bridges predeployed on every interop chain, tried after the configured ones
+var builtinPayloadDecoders = []PayloadDecoderConfig{
+	{
+		Name:      "relayERC20",
+		Target:    &superchainTokenBridge,
+		ABI:       json.RawMessage(`[{"type":"function","name":"relayERC20","inputs":[{"name":"_token","type":"address"},{"name":"_from","type":"address"},{"name":"_to","type":"address"},{"name":"_amount","type":"uint256"}],"outputs":[],"stateMutability":"nonpayable"}]`),
+		Token:     "_token",
+		From:      "_from",
+		Recipient: "_to",
+		Amount:    "_amount",
+	},
+	{
+		Name:      "relayETH",
+		Target:    &superchainETHBridge,
+		ABI:       json.RawMessage(`[{"type":"function","name":"relayETH","inputs":[{"name":"_from","type":"address"},{"name":"_to","type":"address"},{"name":"_amount","type":"uint256"}],"outputs":[],"stateMutability":"nonpayable"}]`),
+		Token:     EtherToken.Hex(),
+		From:      "_from",
+		Recipient: "_to",
+		Amount:    "_amount",
+	},
+}
+
+// Decodes the calls of a target contract. Token, from, recipient and amount name the arguments
+// holding them, token may also be a fixed address, e.g. the target for a single token bridge
+type PayloadDecoderConfig struct {
+	Name      string          `json:"name"`
+	Target    *common.Address `json:"target"` // any target if not set
+	ABI       json.RawMessage `json:"abi"`    // JSON ABI of the functions to decode
+	Token     string          `json:"token"`
+	From      string          `json:"from"`
+	Recipient string          `json:"recipient"`
+	Amount    string          `json:"amount"`
+}
+
+// Decoded `message` of a SentMessage, i.e. the call made on the target once relayed
+type Payload struct {
+	Decoder   string            `json:"decoder"` // "relayERC20", "relayETH" or the name of a configured decoder
+	Method    string            `json:"method"`
+	Token     *common.Address   `json:"token,omitempty"` // zero address for ETH
+	From      *common.Address   `json:"from,omitempty"`  // owner of the tokens on the sender chain
+	Recipient *common.Address   `json:"recipient,omitempty"`
+	Amount    *big.Int          `json:"amount,omitempty"` // in the smallest unit of the token
+	Args      map[string]string `json:"args,omitempty"`   // all the arguments, for configured decoders
+}
+
+type payloadDecoder struct {
+	PayloadDecoderConfig
+	abi        *abi.ABI
+	fixedToken *common.Address
+	builtin    bool
+}
+
+// Configured decoders first, then the builtin ones
+type PayloadRegistry struct {
+	decoders []*payloadDecoder
+}
+
+func NewPayloadRegistry(configs []PayloadDecoderConfig) (*PayloadRegistry, error) {
+	r := &PayloadRegistry{}
+	names := make(map[string]bool)
+	for _, config := range builtinPayloadDecoders {
+		names[config.Name] = true
+	}
+
+	add := func(i int, config PayloadDecoderConfig, builtin bool) error {
+		if config.Name == "" {
+			return fmt.Errorf("payloadDecoders[%d]: name is required", i)
+		}
+		if !builtin && names[config.Name] {
+			return fmt.Errorf("payloadDecoders[%d]: name %q is already used", i, config.Name)
+		}
+		names[config.Name] = true
+
+		if len(config.ABI) == 0 {
+			return fmt.Errorf("payloadDecoders[%d]: abi is required", i)
+		}
+		parsed, err := abi.JSON(bytes.NewReader(config.ABI))
+		if err != nil {
+			return fmt.Errorf("payloadDecoders[%d]: invalid abi: %w", i, err)
+		}
+		if len(parsed.Methods) == 0 {
+			return fmt.Errorf("payloadDecoders[%d]: abi has no function", i)
+		}
+
+		d := &payloadDecoder{PayloadDecoderConfig: config, abi: &parsed, builtin: builtin}
+		if common.IsHexAddress(config.Token) {
+			token := common.HexToAddress(config.Token)
+			d.fixedToken = &token
+		}
+		r.decoders = append(r.decoders, d)
+
+		return nil
+	}
+
+	for i, config := range configs {
+		if err := add(i, config, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, config := range builtinPayloadDecoders {
+		if err := add(0, config, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Returns nil when no decoder knows the call
+func (r *PayloadRegistry) decode(target common.Address, message []byte) *Payload {
+	if r == nil || len(message) < 4 {
+		return nil
+	}
+
+	for _, d := range r.decoders {
+		if d.Target != nil && *d.Target != target {
+			continue
+		}
+
+		method, err := d.abi.MethodById(message[:4])
+		if err != nil {
+			continue
+		}
+
+		args := make(map[string]interface{})
+		if err := method.Inputs.UnpackIntoMap(args, message[4:]); err != nil {
+			continue
+		}
+
+		payload := &Payload{
+			Decoder:   d.Name,
+			Method:    method.Name,
+			Token:     d.fixedToken,
+			From:      addressArg(args, d.From),
+			Recipient: addressArg(args, d.Recipient),
+		}
+		if payload.Token == nil {
+			payload.Token = addressArg(args, d.Token)
+		}
+		if amount, ok := args[d.Amount].(*big.Int); ok {
+			payload.Amount = amount
+		}
+
+		if !d.builtin {
+			payload.Args = make(map[string]string, len(args))
+			for name, value := range args {
+				payload.Args[name] = formatArg(value)
+			}
+		}
+
+		return payload
+	}
+
+	return nil
+}
+
+func addressArg(args map[string]interface{}, name string) *common.Address {
+	if address, ok := args[name].(common.Address); ok {
+		return &address
+	}
+	return nil
+}
+
+func formatArg(value interface{}) string {
+	switch v := value.(type) {
+	case common.Address:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	case [32]byte:
+		return hexutil.Encode(v[:])
+	case *big.Int:
+		return v.String()
+	default:
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// `successfulMessages` calls per batch request
+const reconcileBatchSize = 100
+
+// Arguments hashed by the messenger to identify a message, see Hashing.hashL2toL2CrossDomainMessage
+var messageHashArguments abi.Arguments
+
+func newMessageHashArguments() (abi.Arguments, error) {
+	var args abi.Arguments
+	for _, arg := range []struct{ name, kind string }{
+		{"destination", "uint256"},
+		{"source", "uint256"},
+		{"nonce", "uint256"},
+		{"sender", "address"},
+		{"target", "address"},
+		{"message", "bytes"},
+	} {
+		t, err := abi.NewType(arg.kind, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, abi.Argument{Name: arg.name, Type: t})
+	}
+
+	return args, nil
+}
+
+// keccak256(abi.encode(destination, source, nonce, sender, target, message)) of a SentMessage log
+func (agg *Aggregator) sentMessageHash(data map[string]interface{}) (common.Hash, error) {
+	destination, _ := data["destination"].(*big.Int)
+	nonce, _ := data["messageNonce"].(*big.Int)
+	sender, _ := data["sender"].(common.Address)
+	target, _ := data["target"].(common.Address)
+	message, _ := data["message"].([]byte)
+
+	if destination == nil || nonce == nil {
+		return common.Hash{}, fmt.Errorf("can't hash SentMessage %v", data)
+	}
+
+	encoded, err := messageHashArguments.Pack(destination, agg.Sender.ChainId, nonce, sender, target, message)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// A message the monitor considered pending or expired, although the messenger had relayed it
+type Discrepancy struct {
+	Id             Identifier     `json:"id"`
+	MessageHash    common.Hash    `json:"messageHash"`
+	Target         common.Address `json:"target"`
+	PreviousStatus MessageStatus  `json:"previousStatus"`
+	DetectedAt     time.Time      `json:"detectedAt"`
+}
+
+// Keeps the last `size` discrepancies and the counts since the monitor started
+type Reconciler struct {
+	lastRun time.Time
+	checked uint64 // messages checked
+	total   uint64 // discrepancies found
+	recent  []Discrepancy
+	size    int
+	window  uint64 // discrepancies since the last alert check
+}
+
+func NewReconciler(size int) *Reconciler {
+	return &Reconciler{size: size}
+}
+
+func (r *Reconciler) add(d Discrepancy) {
+	r.total++
+	r.window++
+
+	r.recent = append(r.recent, d)
+	if len(r.recent) > r.size {
+		r.recent = r.recent[len(r.recent)-r.size:]
+	}
+}
+
+func (r *Reconciler) check(config *Config, pair string) AlertCheck {
+	window := r.window
+	r.window = 0
+
+	return AlertCheck{
+		Rule:      "missedRelays",
+		Type:      "Missed Relays",
+		Severity:  SeverityWarning,
+		Pair:      pair,
+		Firing:    window > config.AlertMissedRelaysMin,
+		Value:     fmt.Sprintf("%d", window),
+		Threshold: fmt.Sprintf("%d", config.AlertMissedRelaysMin),
+	}
+}
+
+// Periodically asks the messenger on the receiver whether the messages still pending, or
+// expired, were relayed, to catch the relays missed by log ingestion
+func (agg *Aggregator) WatchReconciliation(errChan chan error) {
+	for {
+		if !agg.sleep(time.Second * time.Duration(agg.config.ReconcileTime)) {
+			return
+		}
+
+		if err := agg.Reconcile(); err != nil {
+			errChan <- fmt.Errorf("reconcile: %w", err)
+		}
+	}
+}
+
+func (agg *Aggregator) Reconcile() error {
+	// pending messages get some time to be relayed and ingested first
+	minAge := time.Duration(agg.config.ReconcileTime) * time.Second
+
+	var ids []Identifier
+	var hashes []common.Hash
+
+	agg.mu.RLock()
+	for _, record := range agg.messages.pending {
+		if record.MessageHash != nil && time.Since(record.SentAt) >= minAge {
+			ids = append(ids, record.Id)
+			hashes = append(hashes, *record.MessageHash)
+		}
+	}
+	for _, record := range agg.messages.finished {
+		if record.MessageHash != nil && record.Status == MessageExpired {
+			ids = append(ids, record.Id)
+			hashes = append(hashes, *record.MessageHash)
+		}
+	}
+	agg.mu.RUnlock()
+
+	messenger := agg.receiverMessengerContract
+	relayed := make([]bool, len(hashes))
+
+	for start := 0; start < len(hashes); start += reconcileBatchSize {
+		end := min(start+reconcileBatchSize, len(hashes))
+
+		inputs := make([][]byte, 0, end-start)
+		for _, hash := range hashes[start:end] {
+			input, err := messenger.ABI.Pack("successfulMessages", hash)
+			if err != nil {
+				return err
+			}
+			inputs = append(inputs, input)
+		}
+
+		outputs, errs, err := messenger.Chain.BatchCall(messenger.Address, inputs)
+		if err != nil {
+			return err
+		}
+
+		for i, output := range outputs {
+			if errs[i] != nil {
+				log.Printf("reconcile: failed to check %s: %v", hashes[start+i].Hex(), errs[i])
+				continue
+			}
+
+			values, err := messenger.ABI.Unpack("successfulMessages", output)
+			if err != nil || len(values) != 1 {
+				log.Printf("reconcile: failed to check %s", hashes[start+i].Hex())
+				continue
+			}
+			relayed[start+i], _ = values[0].(bool)
+		}
+	}
+
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	agg.reconciler.lastRun = time.Now().UTC()
+	agg.reconciler.checked += uint64(len(hashes))
+
+	for i, id := range ids {
+		if relayed[i] {
+			agg.reconciled(id)
+		}
+	}
+
+	return nil
+}
+
+// Called with the aggregator lock held
+func (agg *Aggregator) reconciled(id Identifier) {
+	record, previous := agg.messages.reconciled(id)
+	if record == nil {
+		// the relay log was ingested meanwhile
+		return
+	}
+
+	// block stats may have been purged already
+	if bs, ok := agg.BlockStats[record.SentBlock]; ok {
+		bs.Reconciled += 1
+		agg.BlockStats[record.SentBlock] = bs
+	}
+
+	agg.slo.observe(record, time.Now())
+	agg.breakdowns.transition(record, previous)
+
+	d := Discrepancy{
+		Id:             id,
+		MessageHash:    *record.MessageHash,
+		Target:         record.Target,
+		PreviousStatus: previous,
+		DetectedAt:     time.Now().UTC(),
+	}
+	agg.reconciler.add(d)
+
+	eventBus.Publish(EventReconciled, agg.Name(), record.Target.Hex(), d)
+
+	log.Printf("reconcile: %s was relayed but %s", d.MessageHash.Hex(), previous)
+}
+
+type ReconciliationReport struct {
+	Pair          string        `json:"pair"`
+	LastRun       *time.Time    `json:"lastRun"` // null until the first run
+	Checked       uint64        `json:"checked"` // message checks since the monitor started
+	Total         uint64        `json:"total"`   // discrepancies since the monitor started
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Returns the recent discrepancies newest first
+func (agg *Aggregator) Reconciliation(limit int) ReconciliationReport {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	r := agg.reconciler
+	report := ReconciliationReport{
+		Pair:          agg.Name(),
+		Checked:       r.checked,
+		Total:         r.total,
+		Discrepancies: make([]Discrepancy, 0),
+	}
+	if !r.lastRun.IsZero() {
+		lastRun := r.lastRun
+		report.LastRun = &lastRun
+	}
+
+	for i := len(r.recent) - 1; i >= 0 && (limit == 0 || len(report.Discrepancies) < limit); i-- {
+		report.Discrepancies = append(report.Discrepancies, r.recent[i])
+	}
+
+	return report
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// executions waiting for their receipt, more are dropped instead of blocking the aggregator
+	relayerQueueSize = 4096
+	// recent execution transactions, so the gas of transactions executing several messages counts once
+	relayerSeenTxs     = 4096
+	receiptMaxAttempts = 3
+	// executions queued while a batch is fetched are fetched together in the next one
+	receiptBatchSize = 100
+)
+
+// Performance of a single relayer, since the monitor started
+type RelayerStat struct {
+	Address      string    `json:"address"` // or "other" for the relayers out of the top N
+	Messages     uint64    `json:"messages"`
+	Transactions uint64    `json:"transactions"`
+	Failed       uint64    `json:"failed"`      // execution transactions that reverted
+	FailureRate  float64   `json:"failureRate"` // failed / (transactions + failed)
+	GasUsed      uint64    `json:"gasUsed"`
+	L1Fee        *big.Int  `json:"l1Fee"`        // in wei
+	Cost         *big.Int  `json:"cost"`         // in wei, including the L1 fee
+	TotalLatency uint64    `json:"totalLatency"` // in seconds, over the messages with a known latency
+	Latencies    uint64    `json:"latencies"`    // messages with a known latency
+	AvgLatency   float64   `json:"avgLatency"`
+	MaxLatency   uint64    `json:"maxLatency"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
+
+func (s *RelayerStat) add(o *RelayerStat) {
+	s.Messages += o.Messages
+	s.Transactions += o.Transactions
+	s.Failed += o.Failed
+	s.GasUsed += o.GasUsed
+	s.addFees(o.L1Fee, o.Cost)
+	s.TotalLatency += o.TotalLatency
+	s.Latencies += o.Latencies
+	s.MaxLatency = max(s.MaxLatency, o.MaxLatency)
+	if o.LastSeen.After(s.LastSeen) {
+		s.LastSeen = o.LastSeen
+	}
+}
+
+// Amounts are replaced rather than mutated, since copies of the stat share them
+func (s *RelayerStat) addFees(l1Fee, cost *big.Int) {
+	s.L1Fee = new(big.Int).Add(s.L1Fee, l1Fee)
+	s.Cost = new(big.Int).Add(s.Cost, cost)
+}
+
+func (s *RelayerStat) key() string { return s.Address }
+
+func (s *RelayerStat) activity() (uint64, time.Time) { return s.Messages, s.LastSeen }
+
+func newRelayerStat(address string) RelayerStat {
+	return RelayerStat{Address: address, L1Fee: new(big.Int), Cost: new(big.Int)}
+}
+
+func (s *RelayerStat) finalize() {
+	if attempts := s.Transactions + s.Failed; attempts > 0 {
+		s.FailureRate = float64(s.Failed) / float64(attempts)
+	}
+	if s.Latencies > 0 {
+		s.AvgLatency = float64(s.TotalLatency) / float64(s.Latencies)
+	}
+}
+
+type relayerJob struct {
+	id     Identifier // message executed
+	txHash common.Hash
+}
+
+// Identifies the relayer of every executed message through the receipt of its execution transaction.
+// Stats are bounded to the `size` most active relayers, like the breakdowns
+type RelayerTracker struct {
+	boundedStats[RelayerStat, *RelayerStat]
+	jobs    chan relayerJob
+	seenTxs map[common.Hash]bool
+	txOrder []common.Hash
+
+	// receipts of messages executed before their SentMessage was seen
+	early      map[Identifier]*Receipt
+	earlyOrder []Identifier
+}
+
+func NewRelayerTracker(size int) *RelayerTracker {
+	return &RelayerTracker{
+		boundedStats: newBoundedStats[RelayerStat, *RelayerStat](size, newRelayerStat("other"), func(key common.Address) RelayerStat {
+			return newRelayerStat(key.Hex())
+		}),
+		jobs:    make(chan relayerJob, relayerQueueSize),
+		seenTxs: make(map[common.Hash]bool),
+		early:   make(map[Identifier]*Receipt),
+	}
+}
+
+// Never blocks, called with the aggregator lock held
+func (t *RelayerTracker) enqueue(id Identifier, txHash common.Hash) {
+	select {
+	case t.jobs <- relayerJob{id: id, txHash: txHash}:
+	default:
+		log.Printf("relayers: queue full, dropping execution %s", txHash.Hex())
+	}
+}
+
+// Returns whether the transaction was already accounted
+func (t *RelayerTracker) seen(hash common.Hash) bool {
+	if t.seenTxs[hash] {
+		return true
+	}
+
+	t.seenTxs[hash] = true
+	t.txOrder = append(t.txOrder, hash)
+	if len(t.txOrder) > relayerSeenTxs {
+		delete(t.seenTxs, t.txOrder[0])
+		t.txOrder = t.txOrder[1:]
+	}
+
+	return false
+}
+
+// The record is nil when the execution was seen before the message was sent.
+// Returns whether the transaction is new, i.e. this is the first message it executes
+func (t *RelayerTracker) executed(id Identifier, record *MessageRecord, receipt *Receipt) (newTx bool) {
+	stat := t.get(receipt.From, true)
+	stat.Messages++
+	stat.LastSeen = time.Now().UTC()
+
+	if newTx = !t.seen(receipt.TxHash); newTx {
+		stat.Transactions++
+		stat.GasUsed += uint64(receipt.GasUsed)
+		stat.addFees(receipt.Fees())
+	}
+
+	if record == nil {
+		t.early[id] = receipt
+		t.earlyOrder = append(t.earlyOrder, id)
+		if len(t.earlyOrder) > relayerSeenTxs {
+			delete(t.early, t.earlyOrder[0])
+			t.earlyOrder = t.earlyOrder[1:]
+		}
+		return
+	}
+
+	t.attach(record, receipt)
+	return
+}
+
+func (t *RelayerTracker) attach(record *MessageRecord, receipt *Receipt) {
+	relayer, tx := receipt.From, receipt.TxHash
+	record.Relayer = &relayer
+	record.ExecutionTx = &tx
+	t.observeLatency(record)
+}
+
+// Attaches the relayer of a message executed before it was seen on the sender
+func (t *RelayerTracker) sent(record *MessageRecord) {
+	if receipt, ok := t.early[record.Id]; ok {
+		delete(t.early, record.Id)
+		t.attach(record, receipt)
+	}
+}
+
+// Reverted execution transactions don't emit logs, so they are reported by the failure tracking
+func (t *RelayerTracker) failed(receipt *Receipt) {
+	stat := t.get(receipt.From, true)
+	stat.Failed++
+	stat.GasUsed += uint64(receipt.GasUsed)
+	stat.addFees(receipt.Fees())
+	stat.LastSeen = time.Now().UTC()
+}
+
+// Counts the latency once both the relayer and the latency of the message are known
+func (t *RelayerTracker) observeLatency(record *MessageRecord) {
+	if record.relayerCounted || record.Relayer == nil || record.Latency == nil {
+		return
+	}
+	record.relayerCounted = true
+
+	stat := t.get(*record.Relayer, false)
+	stat.TotalLatency += *record.Latency
+	stat.Latencies++
+	stat.MaxLatency = max(stat.MaxLatency, *record.Latency)
+}
+
+// Returns the `limit` most active relayers, and the sum of the rest
+func (t *RelayerTracker) Top(limit int) (items []RelayerStat, other RelayerStat) {
+	items, other = t.top(limit)
+	for i := range items {
+		items[i].finalize()
+	}
+	other.finalize()
+
+	return
+}
+
+type RelayerReport struct {
+	Pair  string        `json:"pair"`
+	Items []RelayerStat `json:"items"`
+	Other RelayerStat   `json:"other"`
+}
+
+func (agg *Aggregator) Relayers(limit int) RelayerReport {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	items, other := agg.relayers.Top(limit)
+
+	return RelayerReport{Pair: agg.Name(), Items: items, Other: other}
+}
+
+// Fetches the receipts of the executions queued by the aggregator, in batches
+func (agg *Aggregator) WatchRelayers() {
+	for {
+		var job relayerJob
+		select {
+		case job = <-agg.relayers.jobs:
+		case <-agg.done:
+			return
+		}
+
+		jobs := []relayerJob{job}
+	drain:
+		for len(jobs) < receiptBatchSize {
+			select {
+			case next := <-agg.relayers.jobs:
+				jobs = append(jobs, next)
+			default:
+				break drain
+			}
+		}
+
+		// messages executed by the same transaction share its receipt
+		var missing []common.Hash
+		receipts := make(map[common.Hash]*Receipt)
+		for _, job := range jobs {
+			if _, ok := receipts[job.txHash]; !ok {
+				receipts[job.txHash] = nil
+				missing = append(missing, job.txHash)
+			}
+		}
+
+		causes := make(map[common.Hash]error) // of the last attempt, nil when not found
+		for attempt := 0; len(missing) > 0 && attempt < receiptMaxAttempts; attempt++ {
+			if attempt > 0 && !agg.sleep(time.Second<<(attempt-1)) {
+				return
+			}
+
+			fetched, errs, err := agg.Receiver.GetReceipts(missing)
+			if err != nil {
+				log.Printf("relayers: failed to fetch %d receipts: %v", len(missing), err)
+				continue
+			}
+
+			var next []common.Hash
+			for i, receipt := range fetched {
+				if receipt == nil {
+					causes[missing[i]] = errs[i]
+					next = append(next, missing[i])
+				} else {
+					receipts[missing[i]] = receipt
+				}
+			}
+			missing = next
+		}
+
+		for _, hash := range missing {
+			if cause := causes[hash]; cause != nil {
+				log.Printf("relayers: failed to fetch receipt %s: %v", hash.Hex(), cause)
+			} else {
+				log.Printf("relayers: failed to fetch receipt %s: not found", hash.Hex())
+			}
+		}
+
+		agg.mu.Lock()
+		for _, job := range jobs {
+			receipt := receipts[job.txHash]
+			if receipt == nil {
+				continue
+			}
+
+			if record := agg.messages.get(job.id); record == nil || record.Relayer == nil {
+				newTx := agg.relayers.executed(job.id, record, receipt)
+				agg.costs.observe(receipt, newTx)
+			}
+		}
+		agg.mu.Unlock()
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// "Objective" of the messages must be relayed within "LatencyTarget" seconds
+type SLO struct {
+	Name          string  `json:"name"`
+	Objective     float64 `json:"objective"`
+	LatencyTarget uint64  `json:"latencyTarget"`
+	FastBurnRate  float64 `json:"fastBurnRate"` // alert threshold over the 1h window
+	SlowBurnRate  float64 `json:"slowBurnRate"` // alert threshold over the 1d window
+}
+
+const (
+	defaultFastBurnRate = 14.4
+	defaultSlowBurnRate = 3
+)
+
+var sloWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"1d", 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+type sloBucket struct {
+	good  uint64
+	total uint64
+}
+
+type SLOWindowReport struct {
+	Total                uint64  `json:"total"`
+	Good                 uint64  `json:"good"`
+	Compliance           float64 `json:"compliance"`
+	BurnRate             float64 `json:"burnRate"`
+	ErrorBudgetRemaining float64 `json:"errorBudgetRemaining"`
+}
+
+type SLOReport struct {
+	Pair                 string                     `json:"pair"`
+	Name                 string                     `json:"name"`
+	Objective            float64                    `json:"objective"`
+	LatencyTarget        uint64                     `json:"latencyTarget"`
+	ErrorBudgetRemaining float64                    `json:"errorBudgetRemaining"` // over the longest window
+	Windows              map[string]SLOWindowReport `json:"windows"`
+}
+
+// Counts good and bad messages for every SLO, in one minute buckets
+type SLOTracker struct {
+	mu      sync.Mutex
+	defs    []SLO
+	buckets []map[int64]*sloBucket
+}
+
+func NewSLOTracker(defs []SLO) *SLOTracker {
+	t := &SLOTracker{defs: defs}
+
+	for range defs {
+		t.buckets = append(t.buckets, make(map[int64]*sloBucket))
+	}
+
+	return t
+}
+
+// Counts the message for every SLO whose outcome is now known: relayed messages are good
+// if within the latency target, unrelayed ones are bad once the target has passed or once
+// they expire, since expired records aren't observed again
+func (t *SLOTracker) observe(record *MessageRecord, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if record.sloDone == nil {
+		record.sloDone = make([]bool, len(t.defs))
+	}
+
+	minute := now.Unix() / 60
+
+	for i, def := range t.defs {
+		if record.sloDone[i] {
+			continue
+		}
+
+		var good bool
+		if record.Latency != nil {
+			good = *record.Latency <= def.LatencyTarget
+		} else if record.Status == MessageExpired || now.Sub(record.SentAt) > time.Duration(def.LatencyTarget)*time.Second {
+			good = false
+		} else {
+			continue
+		}
+
+		bucket, ok := t.buckets[i][minute]
+		if !ok {
+			bucket = &sloBucket{}
+			t.buckets[i][minute] = bucket
+		}
+
+		bucket.total += 1
+		if good {
+			bucket.good += 1
+		}
+		record.sloDone[i] = true
+	}
+}
+
+func (t *SLOTracker) window(i int, d time.Duration, now time.Time) (w SLOWindowReport) {
+	from := now.Add(-d).Unix() / 60
+
+	for minute, bucket := range t.buckets[i] {
+		if minute > from {
+			w.Total += bucket.total
+			w.Good += bucket.good
+		}
+	}
+
+	budget := 1 - t.defs[i].Objective
+	w.Compliance = 1
+	if w.Total > 0 {
+		w.Compliance = float64(w.Good) / float64(w.Total)
+	}
+
+	errorRate := 1 - w.Compliance
+	w.BurnRate = errorRate / budget
+	w.ErrorBudgetRemaining = 1 - w.BurnRate
+
+	return
+}
+
+func (t *SLOTracker) Report(pair string) []SLOReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	reports := make([]SLOReport, 0, len(t.defs))
+
+	for i, def := range t.defs {
+		report := SLOReport{
+			Pair:          pair,
+			Name:          def.Name,
+			Objective:     def.Objective,
+			LatencyTarget: def.LatencyTarget,
+			Windows:       make(map[string]SLOWindowReport),
+		}
+
+		for _, w := range sloWindows {
+			report.Windows[w.name] = t.window(i, w.duration, now)
+		}
+		report.ErrorBudgetRemaining = report.Windows[sloWindows[len(sloWindows)-1].name].ErrorBudgetRemaining
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+// Burn rate alerts, and cleanup of buckets older than the longest window
+func (t *SLOTracker) Checks(pair string) (checks []AlertCheck) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	oldest := now.Add(-sloWindows[len(sloWindows)-1].duration).Unix() / 60
+
+	for i, def := range t.defs {
+		for minute := range t.buckets[i] {
+			if minute <= oldest {
+				delete(t.buckets[i], minute)
+			}
+		}
+
+		fast := t.window(i, time.Hour, now)
+		slow := t.window(i, 24*time.Hour, now)
+
+		checks = append(checks, AlertCheck{
+			Rule:      "sloFastBurn:" + def.Name,
+			Type:      fmt.Sprintf("SLO %s Fast Burn", def.Name),
+			Severity:  SeverityCritical,
+			Pair:      pair,
+			Firing:    fast.BurnRate > def.FastBurnRate,
+			Value:     fmt.Sprintf("%.2f", fast.BurnRate),
+			Threshold: fmt.Sprintf("%.2f", def.FastBurnRate),
+		}, AlertCheck{
+			Rule:      "sloSlowBurn:" + def.Name,
+			Type:      fmt.Sprintf("SLO %s Slow Burn", def.Name),
+			Severity:  SeverityWarning,
+			Pair:      pair,
+			Firing:    slow.BurnRate > def.SlowBurnRate,
+			Value:     fmt.Sprintf("%.2f", slow.BurnRate),
+			Threshold: fmt.Sprintf("%.2f", def.SlowBurnRate),
+		})
+	}
+
+	return
+}
+
+// Counts pending messages that already missed their target, then evaluates the burn rate alerts
+func (agg *Aggregator) WatchSLOs(errChan chan<- error) {
+	config := agg.config
+
+	for {
+		if !agg.sleep(time.Second * time.Duration(config.SLOCheckTime)) {
+			return
+		}
+
+		now := time.Now()
+		agg.mu.Lock()
+		for _, record := range agg.messages.pending {
+			agg.slo.observe(record, now)
+		}
+		agg.mu.Unlock()
+
+		stats := agg.AggregateLatestBlocks(config.AggregateBlockAmount)
+
+		for _, check := range agg.slo.Checks(agg.Name()) {
+			if err := alertManager.Evaluate(check, stats); err != nil {
+				errChan <- err
+			}
+		}
+	}
+}
+
+func validateSLOs(defs []SLO) error {
+	names := make(map[string]bool)
+
+	for i := range defs {
+		def := &defs[i]
+
+		if def.Name == "" || names[def.Name] {
+			return fmt.Errorf("every SLO needs a unique name")
+		}
+		names[def.Name] = true
+
+		if def.Objective <= 0 || def.Objective >= 1 {
+			return fmt.Errorf("objective of SLO %s must be between 0 and 1", def.Name)
+		}
+
+		if def.LatencyTarget == 0 {
+			return fmt.Errorf("latencyTarget of SLO %s must be positive", def.Name)
+		}
+
+		if def.FastBurnRate == 0 {
+			def.FastBurnRate = defaultFastBurnRate
+		}
+
+		if def.SlowBurnRate == 0 {
+			def.SlowBurnRate = defaultSlowBurnRate
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+// keeps idle connections from being closed by proxies
+const streamKeepAlive = 15 * time.Second
+
+var validStreamTypes = map[string]bool{
+	EventSent:       true,
+	EventExecuting:  true,
+	EventRelayed:    true,
+	EventPaired:     true,
+	EventExpired:    true,
+	EventFailed:     true,
+	EventReconciled: true,
+	EventGap:        true,
+	EventReplay:     true,
+	EventAlert:      true,
+}
+
+// CheckOrigin is set from the CORS allow-list when the API starts
+var wsUpgrader = websocket.Upgrader{}
+
+func streamFilterFromQuery(c echo.Context) (StreamFilter, error) {
+	filter := NewStreamFilter(c.QueryParam("pair"), c.QueryParam("type"), c.QueryParam("target"))
+
+	for t := range filter.Types {
+		if !validStreamTypes[t] {
+			return filter, fmt.Errorf("invalid event type %q", t)
+		}
+	}
+
+	return filter, nil
+}
+
+// Server-Sent Events stream
+func streamRoute(c echo.Context) error {
+	filter, err := streamFilterFromQuery(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	sub := eventBus.Subscribe(filter)
+	defer eventBus.Unsubscribe(sub)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-ticker.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case ev := <-sub.Events:
+			data, err := json.Marshal(ev)
+			if err != nil {
+				return err
+			}
+
+			if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+// WebSocket stream, every event is sent as a JSON text message
+func streamWebSocketRoute(c echo.Context) error {
+	filter, err := streamFilterFromQuery(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// the upgrader already answered the request
+		return nil
+	}
+	defer conn.Close()
+
+	sub := eventBus.Subscribe(filter)
+	defer eventBus.Unsubscribe(sub)
+
+	// the client is not expected to send anything, but reading is needed to notice it left
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return nil
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamKeepAlive)); err != nil {
+				return nil
+			}
+		case ev := <-sub.Events:
+			if err := conn.WriteJSON(ev); err != nil {
+				return nil
+			}
+		}
+	}
+}
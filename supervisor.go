@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// `supervisor_checkMessage` calls per batch request
+const supervisorBatchSize = 100
+
+// Safety levels of op-supervisor, from the least to the most safe
+const (
+	SafetyInvalid     = "invalid"
+	SafetyUnsafe      = "unsafe"
+	SafetyCrossUnsafe = "cross-unsafe"
+	SafetyLocalSafe   = "local-safe"
+	SafetySafe        = "safe" // cross-safe
+	SafetyFinalized   = "finalized"
+)
+
+// Views of the monitor on an executing message, compared with the safety level of the supervisor
+const (
+	ViewPaired   = "paired"   // the monitor saw the SentMessage log of the execution
+	ViewExecuted = "executed" // the monitor only saw the execution
+)
+
+// An executing message to check, as sent to `supervisor_checkMessage`
+type SupervisorMessage struct {
+	Id         Identifier
+	MsgHash    common.Hash
+	ExecutedAt uint64 // timestamp of the executing block
+}
+
+// Heads of a chain as seen by the supervisor, by safety level
+type SupervisorHeads struct {
+	LocalUnsafe uint64 `json:"localUnsafe"`
+	CrossUnsafe uint64 `json:"crossUnsafe"`
+	LocalSafe   uint64 `json:"localSafe"`
+	CrossSafe   uint64 `json:"crossSafe"`
+	Finalized   uint64 `json:"finalized"`
+}
+
+// Queries of the monitor to op-supervisor, an interface so a fake supervisor can stand in for it
+type Supervisor interface {
+	// Returns the safety level of every message, "" for the ones that failed
+	CheckMessages(ctx context.Context, messages []SupervisorMessage) ([]string, error)
+	// Returns the heads of every chain of the dependency set, by chain id
+	SyncStatus(ctx context.Context) (map[uint64]SupervisorHeads, error)
+	// Returns the chain ids of the dependency set
+	DependencySet(ctx context.Context) ([]uint64, error)
+}
+
+type rpcSupervisor struct {
+	client *rpc.Client
+}
+
+func NewSupervisorClient(url string) (Supervisor, error) {
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpcSupervisor{client: client}, nil
+}
+
+// Identifier as encoded by op-supervisor
+type supervisorIdentifier struct {
+	Origin      common.Address `json:"origin"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	LogIndex    hexutil.Uint64 `json:"logIndex"`
+	Timestamp   hexutil.Uint64 `json:"timestamp"`
+	ChainId     *hexutil.Big   `json:"chainID"`
+}
+
+type executingDescriptor struct {
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+}
+
+func (s *rpcSupervisor) CheckMessages(ctx context.Context, messages []SupervisorMessage) ([]string, error) {
+	levels := make([]string, len(messages))
+	batch := make([]rpc.BatchElem, len(messages))
+	for i, m := range messages {
+		id := supervisorIdentifier{
+			Origin:      m.Id.Origin,
+			BlockNumber: hexutil.Uint64(m.Id.BlockNumber),
+			LogIndex:    hexutil.Uint64(m.Id.LogIndex),
+			Timestamp:   hexutil.Uint64(m.Id.Timestamp),
+			ChainId:     (*hexutil.Big)(new(big.Int).SetUint64(m.Id.ChainId)),
+		}
+		batch[i] = rpc.BatchElem{
+			Method: "supervisor_checkMessage",
+			Args:   []interface{}{id, m.MsgHash, executingDescriptor{Timestamp: hexutil.Uint64(m.ExecutedAt)}},
+			Result: &levels[i],
+		}
+	}
+
+	if err := s.client.BatchCallContext(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	for i := range batch {
+		if batch[i].Error != nil {
+			log.Printf("supervisor: failed to check %v: %v", messages[i].Id, batch[i].Error)
+			levels[i] = ""
+		}
+	}
+
+	return levels, nil
+}
+
+// Block number encoded either as a JSON number, or as a hex string like block refs
+type supervisorNumber uint64
+
+func (n *supervisorNumber) UnmarshalJSON(data []byte) error {
+	var hex hexutil.Uint64
+	if err := json.Unmarshal(data, &hex); err == nil {
+		*n = supervisorNumber(hex)
+		return nil
+	}
+
+	var number uint64
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*n = supervisorNumber(number)
+	return nil
+}
+
+type supervisorBlock struct {
+	Number supervisorNumber `json:"number"`
+}
+
+type supervisorChainStatus struct {
+	LocalUnsafe supervisorBlock `json:"localUnsafe"`
+	CrossUnsafe supervisorBlock `json:"crossUnsafe"`
+	LocalSafe   supervisorBlock `json:"localSafe"`
+	CrossSafe   supervisorBlock `json:"crossSafe"`
+	Finalized   supervisorBlock `json:"finalized"`
+}
+
+func (s *rpcSupervisor) SyncStatus(ctx context.Context) (map[uint64]SupervisorHeads, error) {
+	var status struct {
+		Chains map[string]supervisorChainStatus `json:"chains"`
+	}
+	if err := s.client.CallContext(ctx, &status, "supervisor_syncStatus"); err != nil {
+		return nil, err
+	}
+
+	heads := make(map[uint64]SupervisorHeads, len(status.Chains))
+	for key, chain := range status.Chains {
+		chainId, err := strconv.ParseUint(key, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chain id %q", key)
+		}
+		heads[chainId] = SupervisorHeads{
+			LocalUnsafe: uint64(chain.LocalUnsafe.Number),
+			CrossUnsafe: uint64(chain.CrossUnsafe.Number),
+			LocalSafe:   uint64(chain.LocalSafe.Number),
+			CrossSafe:   uint64(chain.CrossSafe.Number),
+			Finalized:   uint64(chain.Finalized.Number),
+		}
+	}
+
+	return heads, nil
+}
+
+func (s *rpcSupervisor) DependencySet(ctx context.Context) ([]uint64, error) {
+	var set dependencySetFile
+	if err := s.client.CallContext(ctx, &set, "supervisor_dependencySetV1"); err != nil {
+		return nil, err
+	}
+
+	return set.chainIds()
+}
+
+// An executing message the supervisor considers invalid, although the monitor paired it with
+// its SentMessage log
+type Disagreement struct {
+	Id         Identifier  `json:"id"`
+	MsgHash    common.Hash `json:"msgHash"`
+	TxHash     common.Hash `json:"txHash"`
+	Block      uint64      `json:"block"` // receiver block
+	Safety     string      `json:"safety"`
+	View       string      `json:"view"`
+	DetectedAt time.Time   `json:"detectedAt"`
+}
+
+type supervisorCheck struct {
+	message  SupervisorMessage
+	txHash   common.Hash
+	block    uint64
+	queuedAt time.Time
+}
+
+// Heads of a chain of the pair, and how far cross-safe lags behind cross-unsafe
+type SupervisorChain struct {
+	ChainId uint64 `json:"chainId"`
+	SupervisorHeads
+	CrossSafeLag uint64 `json:"crossSafeLag"` // in blocks
+}
+
+// Keeps the executions to check, the counts by safety level since the monitor started and the
+// last `size` disagreements
+type SupervisorTracker struct {
+	lastRun  time.Time
+	chains   []SupervisorChain
+	queue    []supervisorCheck // oldest first
+	dropped  uint64            // executions dropped from a full queue
+	checked  uint64
+	failed   uint64
+	levels   map[string]uint64
+	total    uint64 // disagreements
+	recent   []Disagreement
+	size     int
+	window   uint64 // disagreements since the last alert check
+	lagCheck uint64 // highest cross-safe lag since the last alert check
+}
+
+func NewSupervisorTracker(size int) *SupervisorTracker {
+	return &SupervisorTracker{levels: make(map[string]uint64), size: size}
+}
+
+func (t *SupervisorTracker) enqueue(check supervisorCheck) {
+	t.queue = append(t.queue, check)
+	if len(t.queue) > t.size {
+		t.dropped += uint64(len(t.queue) - t.size)
+		t.queue = t.queue[len(t.queue)-t.size:]
+	}
+}
+
+func (t *SupervisorTracker) add(d Disagreement) {
+	t.total++
+	t.window++
+
+	t.recent = append(t.recent, d)
+	if len(t.recent) > t.size {
+		t.recent = t.recent[len(t.recent)-t.size:]
+	}
+}
+
+// Fires when the supervisor disagreed with the monitor since the last check
+func (t *SupervisorTracker) checkDisagreements(pair string) AlertCheck {
+	window := t.window
+	t.window = 0
+
+	return AlertCheck{
+		Rule:      "supervisorMismatch",
+		Type:      "Supervisor Mismatch",
+		Severity:  SeverityCritical,
+		Pair:      pair,
+		Firing:    window > 0,
+		Value:     fmt.Sprintf("%d", window),
+		Threshold: "0",
+	}
+}
+
+// Fires when cross-safe lagged behind cross-unsafe by more than the threshold on a chain of the pair
+func (t *SupervisorTracker) checkLag(config *Config, pair string) AlertCheck {
+	lag := t.lagCheck
+	t.lagCheck = 0
+	for _, chain := range t.chains {
+		lag = max(lag, chain.CrossSafeLag)
+	}
+
+	return AlertCheck{
+		Rule:      "crossSafeLag",
+		Type:      "Cross-Safe Lag",
+		Severity:  SeverityWarning,
+		Pair:      pair,
+		Firing:    lag > config.AlertCrossSafeLagBlocks,
+		Value:     fmt.Sprintf("%d", lag),
+		Threshold: fmt.Sprintf("%d", config.AlertCrossSafeLagBlocks),
+	}
+}
+
+// Periodically asks the supervisor for its heads and the safety level of the executions
+func (agg *Aggregator) WatchSupervisor(errChan chan error) {
+	for {
+		if !agg.sleep(time.Second * time.Duration(agg.config.SupervisorCheckTime)) {
+			return
+		}
+
+		if err := agg.CheckSupervisor(); err != nil {
+			errChan <- fmt.Errorf("supervisor: %w", err)
+		}
+	}
+}
+
+func (agg *Aggregator) CheckSupervisor() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	heads, err := agg.supervisorClient.SyncStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	var chains []SupervisorChain
+	for _, chainId := range []uint64{agg.Sender.ChainId.Uint64(), agg.Receiver.ChainId.Uint64()} {
+		if h, ok := heads[chainId]; ok {
+			chains = append(chains, SupervisorChain{
+				ChainId:         chainId,
+				SupervisorHeads: h,
+				CrossSafeLag:    h.CrossUnsafe - min(h.CrossSafe, h.CrossUnsafe),
+			})
+		}
+	}
+
+	// executions get some time to be indexed by the supervisor first
+	minAge := time.Duration(agg.config.SupervisorCheckTime) * time.Second
+
+	agg.mu.Lock()
+	var checks []supervisorCheck
+	queue := agg.supervisor.queue[:0]
+	for _, check := range agg.supervisor.queue {
+		if time.Since(check.queuedAt) >= minAge {
+			checks = append(checks, check)
+		} else {
+			queue = append(queue, check)
+		}
+	}
+	agg.supervisor.queue = queue
+	agg.mu.Unlock()
+
+	// unchecked executions keep an empty level and count as failed, so an unreachable supervisor
+	// doesn't pile them up
+	levels := make([]string, len(checks))
+	var checkErr error
+	for start := 0; start < len(checks); start += supervisorBatchSize {
+		end := min(start+supervisorBatchSize, len(checks))
+
+		messages := make([]SupervisorMessage, 0, end-start)
+		for _, check := range checks[start:end] {
+			messages = append(messages, check.message)
+		}
+
+		batch, err := agg.supervisorClient.CheckMessages(ctx, messages)
+		if err != nil {
+			checkErr = err
+			break
+		}
+		copy(levels[start:end], batch)
+	}
+
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	t := agg.supervisor
+	t.lastRun = time.Now().UTC()
+	t.chains = chains
+	for _, chain := range chains {
+		t.lagCheck = max(t.lagCheck, chain.CrossSafeLag)
+	}
+
+	for i, check := range checks {
+		if levels[i] == "" {
+			t.failed++
+			continue
+		}
+		t.checked++
+		t.levels[levels[i]]++
+
+		view := ViewExecuted
+		if agg.messages.get(check.message.Id) != nil {
+			view = ViewPaired
+		}
+		if levels[i] != SafetyInvalid || view != ViewPaired {
+			continue
+		}
+
+		d := Disagreement{
+			Id:         check.message.Id,
+			MsgHash:    check.message.MsgHash,
+			TxHash:     check.txHash,
+			Block:      check.block,
+			Safety:     levels[i],
+			View:       view,
+			DetectedAt: time.Now().UTC(),
+		}
+		t.add(d)
+
+		log.Printf("supervisor: %s is %s but was %s", d.TxHash.Hex(), d.Safety, d.View)
+	}
+
+	return checkErr
+}
+
+type SupervisorReport struct {
+	Pair          string            `json:"pair"`
+	LastRun       *time.Time        `json:"lastRun"` // null until the first run
+	Chains        []SupervisorChain `json:"chains"`  // sender and receiver
+	Queued        int               `json:"queued"`  // executions waiting to be checked
+	Dropped       uint64            `json:"dropped"`
+	Checked       uint64            `json:"checked"` // since the monitor started
+	Failed        uint64            `json:"failed"`
+	Levels        map[string]uint64 `json:"levels"` // checked executions by safety level
+	Total         uint64            `json:"total"`  // disagreements since the monitor started
+	Disagreements []Disagreement    `json:"disagreements"`
+}
+
+// Returns the supervisor heads of the chains of the pair, and the recent disagreements newest first
+func (agg *Aggregator) Supervisor(limit int) SupervisorReport {
+	agg.mu.RLock()
+	defer agg.mu.RUnlock()
+
+	t := agg.supervisor
+	report := SupervisorReport{
+		Pair:          agg.Name(),
+		Chains:        append(make([]SupervisorChain, 0, len(t.chains)), t.chains...),
+		Queued:        len(t.queue),
+		Dropped:       t.dropped,
+		Checked:       t.checked,
+		Failed:        t.failed,
+		Levels:        make(map[string]uint64, len(t.levels)),
+		Total:         t.total,
+		Disagreements: make([]Disagreement, 0),
+	}
+	if !t.lastRun.IsZero() {
+		lastRun := t.lastRun
+		report.LastRun = &lastRun
+	}
+	for level, n := range t.levels {
+		report.Levels[level] = n
+	}
+
+	for i := len(t.recent) - 1; i >= 0 && (limit == 0 || len(report.Disagreements) < limit); i-- {
+		report.Disagreements = append(report.Disagreements, t.recent[i])
+	}
+
+	return report
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type rpcRequest struct {
+	Id     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serves JSON-RPC requests, single or batched, with the result of handle
+func fakeSupervisorServer(t *testing.T, handle func(req rpcRequest) (interface{}, error)) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		respond := func(req rpcRequest) rpcResponse {
+			res := rpcResponse{JSONRPC: "2.0", Id: req.Id}
+			result, err := handle(req)
+			if err != nil {
+				res.Error = &rpcError{Code: -32000, Message: err.Error()}
+			} else {
+				res.Result = result
+			}
+			return res
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(body) > 0 && body[0] == '[' {
+			var reqs []rpcRequest
+			if err := json.Unmarshal(body, &reqs); err != nil {
+				t.Error(err)
+				return
+			}
+			responses := make([]rpcResponse, len(reqs))
+			for i, req := range reqs {
+				responses[i] = respond(req)
+			}
+			json.NewEncoder(w).Encode(responses)
+			return
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Error(err)
+			return
+		}
+		json.NewEncoder(w).Encode(respond(req))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRPCSupervisorCheckMessages(t *testing.T) {
+	invalid := common.HexToHash("0xbad")
+	server := fakeSupervisorServer(t, func(req rpcRequest) (interface{}, error) {
+		if req.Method != "supervisor_checkMessage" {
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		if len(req.Params) != 3 {
+			t.Fatalf("expected 3 params, got %d", len(req.Params))
+		}
+
+		var id supervisorIdentifier
+		if err := json.Unmarshal(req.Params[0], &id); err != nil {
+			t.Fatal(err)
+		}
+		if id.ChainId.ToInt().Uint64() != 901 || id.BlockNumber != 12 {
+			t.Errorf("unexpected identifier %+v", id)
+		}
+
+		var msgHash common.Hash
+		json.Unmarshal(req.Params[1], &msgHash)
+		switch msgHash {
+		case invalid:
+			return SafetyInvalid, nil
+		case common.Hash{}:
+			return nil, errors.New("unknown message")
+		default:
+			return SafetyCrossUnsafe, nil
+		}
+	})
+
+	supervisor, err := NewSupervisorClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := Identifier{Origin: common.HexToAddress("0x4200000000000000000000000000000000000023"), BlockNumber: 12, LogIndex: 1, Timestamp: 1730000000, ChainId: 901}
+	levels, err := supervisor.CheckMessages(context.Background(), []SupervisorMessage{
+		{Id: id, MsgHash: common.HexToHash("0x01"), ExecutedAt: 1730000004},
+		{Id: id, MsgHash: invalid, ExecutedAt: 1730000004},
+		{Id: id, ExecutedAt: 1730000004},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{SafetyCrossUnsafe, SafetyInvalid, ""}
+	for i := range expected {
+		if levels[i] != expected[i] {
+			t.Errorf("level %d: expected %q, got %q", i, expected[i], levels[i])
+		}
+	}
+}
+
+func TestRPCSupervisorSyncStatus(t *testing.T) {
+	server := fakeSupervisorServer(t, func(req rpcRequest) (interface{}, error) {
+		return json.RawMessage(`{"chains": {
+			"901": {"localUnsafe": {"number": "0x4ba"}, "crossUnsafe": {"number": "0x4b8"}, "localSafe": {"number": "0x4a6"}, "crossSafe": {"number": "0x4a4"}, "finalized": {"number": "0x44c"}},
+			"902": {"localUnsafe": {"number": 2010}, "crossUnsafe": {"number": 2008}, "localSafe": {"number": 1990}, "crossSafe": {"number": 1988}, "finalized": {"number": 1900}}
+		}}`), nil
+	})
+
+	supervisor, err := NewSupervisorClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	heads, err := supervisor.SyncStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[uint64]SupervisorHeads{
+		901: {LocalUnsafe: 1210, CrossUnsafe: 1208, LocalSafe: 1190, CrossSafe: 1188, Finalized: 1100},
+		902: {LocalUnsafe: 2010, CrossUnsafe: 2008, LocalSafe: 1990, CrossSafe: 1988, Finalized: 1900},
+	}
+	for chainId, h := range expected {
+		if heads[chainId] != h {
+			t.Errorf("chain %d: expected %+v, got %+v", chainId, h, heads[chainId])
+		}
+	}
+}
+
+func TestRPCSupervisorDependencySet(t *testing.T) {
+	server := fakeSupervisorServer(t, func(req rpcRequest) (interface{}, error) {
+		if req.Method != "supervisor_dependencySetV1" {
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		return json.RawMessage(`{"dependencies": {"902": {"chainIndex": 2}, "901": {"chainIndex": 1}}}`), nil
+	})
+
+	supervisor, err := NewSupervisorClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := supervisor.DependencySet(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 901 || ids[1] != 902 {
+		t.Errorf("expected [901 902], got %v", ids)
+	}
+}
+
+// Supervisor answering from memory
+type fakeSupervisor struct {
+	heads   map[uint64]SupervisorHeads
+	levels  map[common.Hash]string
+	batches int
+	failAt  int // batch that fails, disabled if 0
+}
+
+func (s *fakeSupervisor) CheckMessages(ctx context.Context, messages []SupervisorMessage) ([]string, error) {
+	s.batches++
+	if s.batches == s.failAt {
+		return nil, errors.New("supervisor unavailable")
+	}
+
+	levels := make([]string, len(messages))
+	for i, m := range messages {
+		levels[i] = s.levels[m.MsgHash]
+	}
+	return levels, nil
+}
+
+func (s *fakeSupervisor) SyncStatus(ctx context.Context) (map[uint64]SupervisorHeads, error) {
+	return s.heads, nil
+}
+
+func (s *fakeSupervisor) DependencySet(ctx context.Context) ([]uint64, error) {
+	return []uint64{901, 902}, nil
+}
+
+func newSupervisorAggregator(supervisor Supervisor) *Aggregator {
+	config := &Config{MessageHistorySize: 1000, BreakdownSize: 10, AlertCrossSafeLagBlocks: 10}
+	sender := &Chain{ChainId: big.NewInt(901)}
+	receiver := &Chain{ChainId: big.NewInt(902)}
+
+	agg := MakeAggregator(sender, receiver, config)
+	agg.supervisorClient = supervisor
+	return &agg
+}
+
+func queueExecution(agg *Aggregator, id Identifier, msgHash common.Hash, paired bool) {
+	if paired {
+		agg.messages.sent(id)
+	}
+	agg.supervisor.enqueue(supervisorCheck{
+		message:  SupervisorMessage{Id: id, MsgHash: msgHash, ExecutedAt: id.Timestamp + 2},
+		txHash:   msgHash,
+		block:    id.BlockNumber + 100,
+		queuedAt: time.Now().Add(-time.Minute),
+	})
+}
+
+func TestCheckSupervisor(t *testing.T) {
+	invalid := common.HexToHash("0x01")
+	supervisor := &fakeSupervisor{
+		heads: map[uint64]SupervisorHeads{
+			901: {CrossUnsafe: 1208, CrossSafe: 1188},
+			902: {CrossUnsafe: 2008, CrossSafe: 2003},
+		},
+		levels: map[common.Hash]string{
+			invalid:                SafetyInvalid,
+			common.HexToHash("02"): SafetyInvalid,
+			common.HexToHash("03"): SafetySafe,
+		},
+	}
+	agg := newSupervisorAggregator(supervisor)
+
+	queueExecution(agg, Identifier{BlockNumber: 1, Timestamp: 1730000000, ChainId: 901}, invalid, true)
+	queueExecution(agg, Identifier{BlockNumber: 2, Timestamp: 1730000000, ChainId: 901}, common.HexToHash("02"), false)
+	queueExecution(agg, Identifier{BlockNumber: 3, Timestamp: 1730000000, ChainId: 901}, common.HexToHash("03"), true)
+
+	if err := agg.CheckSupervisor(); err != nil {
+		t.Fatal(err)
+	}
+
+	report := agg.Supervisor(0)
+	if report.Checked != 3 || report.Queued != 0 {
+		t.Errorf("expected 3 checked and none queued, got %d and %d", report.Checked, report.Queued)
+	}
+	if report.Levels[SafetyInvalid] != 2 || report.Levels[SafetySafe] != 1 {
+		t.Errorf("unexpected levels %v", report.Levels)
+	}
+
+	// only the paired execution disagrees, the other one was never seen sent
+	if report.Total != 1 || len(report.Disagreements) != 1 {
+		t.Fatalf("expected 1 disagreement, got %d", report.Total)
+	}
+	d := report.Disagreements[0]
+	if d.TxHash != invalid || d.Safety != SafetyInvalid || d.View != ViewPaired {
+		t.Errorf("unexpected disagreement %+v", d)
+	}
+
+	if check := agg.supervisor.checkDisagreements(agg.Name()); !check.Firing {
+		t.Error("expected supervisorMismatch to fire")
+	}
+	if check := agg.supervisor.checkDisagreements(agg.Name()); check.Firing {
+		t.Error("expected supervisorMismatch to resolve without new disagreements")
+	}
+
+	// 20 blocks of cross-safe lag on the sender, above the threshold of 10
+	check := agg.supervisor.checkLag(agg.config, agg.Name())
+	if !check.Firing || check.Value != "20" {
+		t.Errorf("expected crossSafeLag to fire at 20, got %+v", check)
+	}
+
+	supervisor.heads[901] = SupervisorHeads{CrossUnsafe: 1208, CrossSafe: 1200}
+	if err := agg.CheckSupervisor(); err != nil {
+		t.Fatal(err)
+	}
+	if check := agg.supervisor.checkLag(agg.config, agg.Name()); check.Firing {
+		t.Errorf("expected crossSafeLag to resolve at %s", check.Value)
+	}
+}
+
+func TestCheckSupervisorFailedBatch(t *testing.T) {
+	supervisor := &fakeSupervisor{
+		levels: map[common.Hash]string{common.HexToHash("01"): SafetySafe},
+		failAt: 2,
+	}
+	agg := newSupervisorAggregator(supervisor)
+
+	for i := 0; i < supervisorBatchSize+10; i++ {
+		queueExecution(agg, Identifier{BlockNumber: uint64(i), Timestamp: 1730000000, ChainId: 901}, common.HexToHash("01"), true)
+	}
+
+	if err := agg.CheckSupervisor(); err == nil {
+		t.Fatal("expected the failed batch to be reported")
+	}
+
+	report := agg.Supervisor(0)
+	if report.Checked != supervisorBatchSize || report.Failed != 10 {
+		t.Errorf("expected %d checked and 10 failed, got %d and %d", supervisorBatchSize, report.Checked, report.Failed)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Dashboard, a static page that only uses the API so it works without internet access
+//
+//go:embed ui
+var uiFiles embed.FS
+
+var uiHandler = func() http.Handler {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/ui/", http.FileServer(http.FS(files)))
+}()
+
+func uiRedirectRoute(c echo.Context) error {
+	return c.Redirect(http.StatusMovedPermanently, "/ui/")
+}
+
+func uiRoute(c echo.Context) error {
+	uiHandler.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
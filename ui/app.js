@@ -0,0 +1,278 @@
+"use strict";
+
+// Everything shown here comes from the monitor's own API, so the page works offline.
+const REFRESH_MS = 10000;
+const TABLE_LIMIT = 50;
+
+const $ = (id) => document.getElementById(id);
+
+let apiKey = localStorage.getItem("apiKey") || "";
+let pair = localStorage.getItem("pair") || "";
+
+class AuthError extends Error {}
+
+async function api(path) {
+  const headers = apiKey ? { Authorization: "Bearer " + apiKey } : {};
+  const res = await fetch(path, { headers });
+
+  if (res.status === 401 || res.status === 403) {
+    throw new AuthError(await res.text());
+  }
+  // `/readyz` answers 503 with a report when not ready, detailed to `read` keys only
+  if (!res.ok && res.status !== 503) {
+    throw new Error(path + ": " + res.status + " " + (await res.text()));
+  }
+
+  return res.json();
+}
+
+// Scopes the path to the selected pair, required once several pairs are monitored
+function withPair(path) {
+  return pair ? path + (path.includes("?") ? "&" : "?") + "pair=" + encodeURIComponent(pair) : path;
+}
+
+function el(tag, attrs = {}, children = []) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs)) {
+    if (key === "class") node.className = value;
+    else node.setAttribute(key, value);
+  }
+  for (const child of [].concat(children)) {
+    node.append(child instanceof Node ? child : document.createTextNode(child ?? ""));
+  }
+  return node;
+}
+
+function badge(text, cls) {
+  return el("span", { class: "badge " + cls }, text);
+}
+
+function renderTable(table, columns, rows) {
+  table.replaceChildren(el("tr", {}, columns.map((c) => el("th", {}, c.title))));
+
+  if (rows.length === 0) {
+    table.append(el("tr", {}, el("td", { class: "empty", colspan: columns.length }, "Nothing to show")));
+    return;
+  }
+
+  for (const row of rows) {
+    table.append(el("tr", {}, columns.map((c) => el("td", {}, c.value(row)))));
+  }
+}
+
+function formatTime(value) {
+  const date = new Date(value);
+  return isNaN(date) || date.getFullYear() < 2000 ? "-" : date.toLocaleString();
+}
+
+function formatAge(value) {
+  let seconds = Math.max(0, Math.round((Date.now() - new Date(value)) / 1000));
+  const parts = [];
+  for (const [unit, size] of [["d", 86400], ["h", 3600], ["m", 60]]) {
+    if (seconds >= size) {
+      parts.push(Math.floor(seconds / size) + unit);
+      seconds %= size;
+    }
+  }
+  parts.push(seconds + "s");
+  return parts.slice(0, 2).join(" ");
+}
+
+function shortHex(value) {
+  return value && value.length > 14 ? value.slice(0, 8) + "…" + value.slice(-6) : value || "-";
+}
+
+// Minimal SVG line chart, `series` is a list of {name, color, points: [[x, y]]}
+function drawChart(svg, legend, series, formatX) {
+  const width = 800, height = 220, pad = { left: 48, right: 8, top: 8, bottom: 22 };
+  const points = series.flatMap((s) => s.points);
+  svg.replaceChildren();
+  legend.replaceChildren(...series.map((s) => el("span", {}, [el("i", { style: "background:" + s.color }), s.name])));
+
+  const ns = "http://www.w3.org/2000/svg";
+  const svgEl = (tag, attrs, text) => {
+    const node = document.createElementNS(ns, tag);
+    for (const [key, value] of Object.entries(attrs)) node.setAttribute(key, value);
+    if (text !== undefined) node.textContent = text;
+    svg.append(node);
+    return node;
+  };
+
+  if (points.length === 0) {
+    svgEl("text", { x: width / 2, y: height / 2, "text-anchor": "middle" }, "No data yet");
+    return;
+  }
+
+  const xs = points.map((p) => p[0]), ys = points.map((p) => p[1]);
+  const minX = Math.min(...xs), maxX = Math.max(...xs);
+  const maxY = Math.max(1, ...ys);
+  const x = (v) => pad.left + (maxX === minX ? 0.5 : (v - minX) / (maxX - minX)) * (width - pad.left - pad.right);
+  const y = (v) => height - pad.bottom - (v / maxY) * (height - pad.top - pad.bottom);
+
+  for (const tick of [0, 0.5, 1]) {
+    const v = tick * maxY;
+    svgEl("line", { class: "axis", x1: pad.left, x2: width - pad.right, y1: y(v), y2: y(v) });
+    svgEl("text", { x: pad.left - 6, y: y(v) + 4, "text-anchor": "end" }, Math.round(v * 10) / 10);
+  }
+  svgEl("text", { x: pad.left, y: height - 4 }, formatX(minX));
+  svgEl("text", { x: width - pad.right, y: height - 4, "text-anchor": "end" }, formatX(maxX));
+
+  for (const s of series) {
+    if (s.points.length === 0) continue;
+    const d = s.points.map((p, i) => (i ? "L" : "M") + x(p[0]).toFixed(1) + "," + y(p[1]).toFixed(1)).join(" ");
+    svgEl("path", { d, stroke: s.color });
+  }
+}
+
+async function loadThroughput() {
+  const bin = Math.max(1, parseInt($("bin").value, 10) || 10);
+  const page = await api(withPair("/all?bin=" + bin + "&order=desc&limit=120"));
+  const items = page.items.slice().reverse();
+
+  drawChart($("throughput"), $("throughputLegend"), [
+    { name: "relayed", color: "#2563eb", points: items.map((i) => [i.block, i.messageCount]) },
+    { name: "missing reception", color: "#d97706", points: items.map((i) => [i.block, i.missingReception]) },
+    { name: "missing relay", color: "#dc2626", points: items.map((i) => [i.block, i.missingRelay]) },
+  ], (block) => "block " + block);
+}
+
+async function loadLatency() {
+  const bucket = $("bucket").value;
+  const since = Math.floor(Date.now() / 1000) - 120 * bucket;
+  const report = await api(withPair("/latency?bucket=" + bucket + "&since=" + since));
+  const at = (b) => new Date(b.time).getTime();
+
+  $("pair").textContent = report.pair;
+  drawChart($("latency"), $("latencyLegend"), [
+    { name: "p50 (s)", color: "#16a34a", points: report.buckets.map((b) => [at(b), b.p50]) },
+    { name: "p90 (s)", color: "#d97706", points: report.buckets.map((b) => [at(b), b.p90]) },
+    { name: "p99 (s)", color: "#dc2626", points: report.buckets.map((b) => [at(b), b.p99]) },
+  ], (ms) => new Date(ms).toLocaleTimeString());
+}
+
+async function loadHealth() {
+  const readiness = await api(withPair("/readyz"));
+
+  $("ready").replaceWith(Object.assign(
+    badge(readiness.ready ? "ready" : "not ready", readiness.ready ? "ok" : "bad"), { id: "ready" }));
+
+  renderTable($("chains"), [
+    { title: "Chain", value: (c) => c.side + " (" + c.chainId + ")" },
+    { title: "RPC", value: (c) => badge(c.rpcReachable ? "up" : "down", c.rpcReachable ? "ok" : "bad") },
+    { title: "Head", value: (c) => c.head },
+    { title: "Lag", value: (c) => c.headLag },
+    { title: "Last fetch", value: (c) => formatAge(c.lastSuccessfulFetch) + " ago" },
+  ], readiness.chains || []);
+
+  $("problems").replaceChildren(...(readiness.problems || []).map((p) => el("li", {}, p)));
+}
+
+async function loadAlerts() {
+  const [alerts, history] = await Promise.all([api("/alerts"), api("/alerts/history?limit=20")]);
+
+  renderTable($("alerts"), [
+    { title: "Severity", value: (a) => badge(a.severity, a.severity) },
+    { title: "Rule", value: (a) => a.rule },
+    { title: "Value", value: (a) => a.value + " / " + a.threshold },
+    { title: "Since", value: (a) => formatAge(a.since) },
+    { title: "State", value: (a) => a.silenced ? "silenced" : a.acknowledged ? "acknowledged" : "open" },
+  ], alerts);
+
+  renderTable($("history"), [
+    { title: "Time", value: (e) => formatTime(e.time) },
+    { title: "Event", value: (e) => e.event },
+    { title: "Severity", value: (e) => badge(e.severity, e.severity) },
+    { title: "Rule", value: (e) => e.rule },
+    { title: "Pair", value: (e) => e.pair },
+    { title: "Value", value: (e) => e.value + " / " + e.threshold },
+  ], history.reverse());
+}
+
+async function loadInflight() {
+  const inflight = await api(withPair("/inflight"));
+
+  renderTable($("inflight"), [
+    { title: "Token", value: (t) => /^0x0+$/.test(t.token) ? "ETH" : shortHex(t.token) },
+    { title: "Messages", value: (t) => t.messages },
+    { title: "Amount", value: (t) => t.amount.toLocaleString() },
+    { title: "Expired", value: (t) => t.expired.toLocaleString() },
+    { title: "Oldest", value: (t) => t.messages > 0 ? formatAge(t.oldestSentAt) : "-" },
+    { title: "Threshold", value: (t) => t.threshold !== undefined ? t.threshold.toLocaleString() : "-" },
+  ], inflight.tokens);
+}
+
+const messageColumns = [
+  { title: "Sent", value: (m) => formatTime(m.sentAt) },
+  { title: "Block", value: (m) => m.sentBlock },
+  { title: "Log", value: (m) => m.id.logIndex },
+  { title: "Target", value: (m) => shortHex(m.target) },
+  { title: "Status", value: (m) => m.status },
+];
+
+async function loadMessages() {
+  const stuckAge = Math.max(1, parseInt($("stuckAge").value, 10) || 300);
+  const [stuck, pending, relayed, expired, reconciled] = await Promise.all([
+    api(withPair("/messages?status=pending&minAge=" + stuckAge + "&limit=" + TABLE_LIMIT)),
+    api(withPair("/messages?status=pending&limit=" + TABLE_LIMIT)),
+    api(withPair("/messages?status=relayed&limit=" + TABLE_LIMIT)),
+    api(withPair("/messages?status=expired&limit=" + TABLE_LIMIT)),
+    api(withPair("/messages?status=reconciled&limit=" + TABLE_LIMIT)),
+  ]);
+
+  const age = { title: "Pending for", value: (m) => formatAge(m.sentAt) };
+  renderTable($("stuck"), messageColumns.concat(age), stuck);
+  renderTable($("pending"), messageColumns.concat(age), pending);
+
+  const recent = relayed.concat(expired, reconciled)
+    .sort((a, b) => new Date(b.sentAt) - new Date(a.sentAt))
+    .slice(0, TABLE_LIMIT);
+  renderTable($("recent"), messageColumns.concat(
+    { title: "Executed", value: (m) => m.executedAt ? formatTime(m.executedAt) : "-" },
+    { title: "Latency", value: (m) => m.latency !== undefined ? m.latency + "s" : "-" },
+  ), recent);
+}
+
+async function loadPairs() {
+  const names = (await api("/pairs")).map((p) => p.pair);
+  if (!names.includes(pair)) pair = names[0] || "";
+
+  const select = $("pairSelect");
+  select.replaceChildren(...names.map((name) => el("option", { value: name }, name)));
+  select.value = pair;
+  select.hidden = names.length < 2;
+}
+
+async function refresh() {
+  try {
+    await loadPairs();
+    await Promise.all([loadThroughput(), loadLatency(), loadHealth(), loadAlerts(), loadInflight(), loadMessages()]);
+    $("auth").hidden = true;
+    $("updated").textContent = "Updated " + new Date().toLocaleTimeString();
+  } catch (err) {
+    if (err instanceof AuthError) {
+      $("auth").hidden = false;
+      $("authError").textContent = apiKey ? err.message : "";
+    }
+    $("updated").textContent = "Update failed: " + err.message;
+  }
+}
+
+$("auth").addEventListener("submit", (ev) => {
+  ev.preventDefault();
+  apiKey = $("apiKey").value.trim();
+  localStorage.setItem("apiKey", apiKey);
+  refresh();
+});
+
+$("pairSelect").addEventListener("change", () => {
+  pair = $("pairSelect").value;
+  localStorage.setItem("pair", pair);
+  refresh();
+});
+
+for (const id of ["bin", "bucket", "stuckAge"]) {
+  $(id).addEventListener("change", refresh);
+}
+
+refresh();
+setInterval(refresh, REFRESH_MS);